@@ -0,0 +1,265 @@
+package couchdb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrDigestMismatch is returned while reading an attachment whose content does not match its digest.
+var ErrDigestMismatch = errors.New("couchdb: attachment digest mismatch")
+
+// AttachmentReader streams the content of an attachment.
+// It must be closed after reading.
+// The content is verified against the digest while it is read and the final
+// Read returns an error wrapping ErrDigestMismatch if it was corrupted.
+type AttachmentReader struct {
+	io.ReadCloser
+	// ContentType is the MIME type the attachment was stored with.
+	ContentType string
+	// Length is the size of the content or -1 if unknown.
+	Length int64
+	// Digest is the MD5 digest of the stored attachment, e.g. "md5-7mkg+nM0HN26sZkLN8KVSA==".
+	// It is computed over the compressed content when Encoding is set.
+	Digest string
+	// Encoding is "gzip" when CouchDB stores the attachment compressed.
+	// The content is decompressed while it is read.
+	Encoding string
+	// EncodedLength is the size of the compressed content transferred from CouchDB or -1 if unknown.
+	EncodedLength int64
+}
+
+// PutAttachmentReader stores the content read from r as attachment of the document.
+// The content is streamed to CouchDB without holding it in memory.
+// The document is created when it has no revision.
+//
+//	file, err := os.Open("video.mp4")
+//	...
+//	res, err := db.PutAttachmentReader(doc, "video.mp4", "video/mp4", file)
+func (db *Database) PutAttachmentReader(doc CouchDoc, name, contentType string, r io.Reader) (*DocumentResponse, error) {
+	return db.PutAttachmentReaderContext(context.Background(), doc, name, contentType, r)
+}
+
+// PutAttachmentReaderContext is like PutAttachmentReader but includes a context.
+func (db *Database) PutAttachmentReaderContext(ctx context.Context, doc CouchDoc, name, contentType string, r io.Reader) (*DocumentResponse, error) {
+	// the request is not marked as idempotent since retries would buffer the whole content
+	res, err := db.Client.RequestContext(ctx, http.MethodPut, db.attachmentURL(doc.GetID(), name, doc.GetRev()), r, contentType)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	var response DocumentResponse
+	return &response, json.NewDecoder(res.Body).Decode(&response)
+}
+
+// GetAttachment returns the content of an attachment of the latest document revision.
+func (db *Database) GetAttachment(id, name string) (*AttachmentReader, error) {
+	return db.GetAttachmentContext(context.Background(), id, name)
+}
+
+// GetAttachmentContext is like GetAttachment but includes a context.
+func (db *Database) GetAttachmentContext(ctx context.Context, id, name string) (*AttachmentReader, error) {
+	// ask for compressed attachments explicitly, otherwise the transport
+	// decompresses them and hides the encoding
+	header := http.Header{}
+	header.Set("Accept-Encoding", "gzip")
+	res, err := db.Client.request(ctx, http.MethodGet, db.attachmentURL(id, name, ""), nil, header)
+	if err != nil {
+		return nil, err
+	}
+	att := &AttachmentReader{
+		ContentType:   res.Header.Get("Content-Type"),
+		Length:        res.ContentLength,
+		Digest:        attachmentDigest(res.Header),
+		EncodedLength: -1,
+	}
+	// the digest of a compressed attachment is computed over the stored gzip bytes,
+	// so the transferred content is verified before it is decompressed
+	verified := &verifyingReader{
+		Reader: res.Body,
+		Closer: res.Body,
+		hash:   md5.New(),
+		want:   decodeDigest(att.Digest),
+	}
+	att.ReadCloser = verified
+	if res.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(verified)
+		if err != nil {
+			res.Body.Close()
+			return nil, err
+		}
+		att.ReadCloser = struct {
+			io.Reader
+			io.Closer
+		}{zr, res.Body}
+		att.Encoding = "gzip"
+		att.EncodedLength = res.ContentLength
+		att.Length = -1
+	}
+	return att, nil
+}
+
+// PutAttachmentIfChanged uploads the attachment unless CouchDB already stores the same content.
+// The digest of r is computed first and compared to the stored one,
+// afterwards r is rewound and streamed to CouchDB with a Content-MD5 header.
+// changed is false and res is nil when the upload was skipped.
+// Attachments CouchDB stores compressed, like text/plain or application/json,
+// have a digest of the compressed content and are always uploaded again.
+func (db *Database) PutAttachmentIfChanged(doc CouchDoc, name, contentType string, r io.ReadSeeker) (res *DocumentResponse, changed bool, err error) {
+	return db.PutAttachmentIfChangedContext(context.Background(), doc, name, contentType, r)
+}
+
+// PutAttachmentIfChangedContext is like PutAttachmentIfChanged but includes a context.
+func (db *Database) PutAttachmentIfChangedContext(ctx context.Context, doc CouchDoc, name, contentType string, r io.ReadSeeker) (*DocumentResponse, bool, error) {
+	start, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, false, err
+	}
+	hash := md5.New()
+	size, err := io.Copy(hash, r)
+	if err != nil {
+		return nil, false, err
+	}
+	sum := hash.Sum(nil)
+	if doc.GetRev() != "" {
+		stored, err := db.attachmentStub(ctx, doc.GetID(), name)
+		if err != nil {
+			return nil, false, err
+		}
+		// compressed attachments have the digest of the gzip bytes which cannot be compared
+		if stored != nil && stored.Encoding == "" && bytes.Equal(decodeDigest(stored.Digest), sum) {
+			return nil, false, nil
+		}
+	}
+	if _, err := r.Seek(start, io.SeekStart); err != nil {
+		return nil, false, err
+	}
+	header := http.Header{}
+	header.Set("Content-Type", contentType)
+	header.Set("Content-MD5", base64.StdEncoding.EncodeToString(sum))
+	res, err := db.Client.request(ctx, http.MethodPut, db.attachmentURL(doc.GetID(), name, doc.GetRev()), &sizedReader{r, size}, header)
+	if err != nil {
+		return nil, false, err
+	}
+	defer res.Body.Close()
+	var response DocumentResponse
+	return &response, true, json.NewDecoder(res.Body).Decode(&response)
+}
+
+// attachmentStub returns the stub of an attachment of the latest document revision
+// with its encoding or nil if the document or attachment does not exist.
+func (db *Database) attachmentStub(ctx context.Context, id, name string) (*Attachment, error) {
+	u := fmt.Sprintf("%s/%s?att_encoding_info=true", url.PathEscape(db.Name), url.PathEscape(id))
+	res, err := db.Client.RequestContext(ctx, http.MethodGet, u, nil, "")
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	var doc struct {
+		Attachments map[string]Attachment `json:"_attachments"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&doc); err != nil {
+		return nil, err
+	}
+	a, ok := doc.Attachments[name]
+	if !ok {
+		return nil, nil
+	}
+	return &a, nil
+}
+
+// Digest returns the digest of the content in the format CouchDB uses for attachments.
+// It can be compared to Attachment.Digest to find out whether an attachment has to be uploaded.
+func Digest(r io.Reader) (string, error) {
+	hash := md5.New()
+	if _, err := io.Copy(hash, r); err != nil {
+		return "", err
+	}
+	return "md5-" + base64.StdEncoding.EncodeToString(hash.Sum(nil)), nil
+}
+
+// DeleteAttachment removes an attachment from the document.
+func (db *Database) DeleteAttachment(doc CouchDoc, name string) (*DocumentResponse, error) {
+	return db.DeleteAttachmentContext(context.Background(), doc, name)
+}
+
+// DeleteAttachmentContext is like DeleteAttachment but includes a context.
+func (db *Database) DeleteAttachmentContext(ctx context.Context, doc CouchDoc, name string) (*DocumentResponse, error) {
+	// deleting from a given revision cannot be applied twice
+	res, err := db.Client.RequestContext(idempotent(ctx), http.MethodDelete, db.attachmentURL(doc.GetID(), name, doc.GetRev()), nil, "")
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	var response DocumentResponse
+	return &response, json.NewDecoder(res.Body).Decode(&response)
+}
+
+// attachmentURL returns the path of an attachment with an optional revision.
+func (db *Database) attachmentURL(id, name, rev string) string {
+	u := fmt.Sprintf("%s/%s/%s", url.PathEscape(db.Name), url.PathEscape(id), url.PathEscape(name))
+	if rev != "" {
+		u += "?" + url.Values{"rev": {rev}}.Encode()
+	}
+	return u
+}
+
+// attachmentDigest returns the digest from the Content-MD5 header or the ETag
+// which both hold the base64 encoded MD5 sum of the attachment.
+func attachmentDigest(header http.Header) string {
+	sum := header.Get("Content-MD5")
+	if sum == "" {
+		sum = strings.Trim(header.Get("ETag"), `"`)
+	}
+	if sum == "" {
+		return ""
+	}
+	return "md5-" + sum
+}
+
+// decodeDigest returns the MD5 sum of a digest or nil if it is no MD5 digest.
+func decodeDigest(digest string) []byte {
+	if !strings.HasPrefix(digest, "md5-") {
+		return nil
+	}
+	sum, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(digest, "md5-"))
+	if err != nil || len(sum) != md5.Size {
+		return nil
+	}
+	return sum
+}
+
+// verifyingReader hashes the content while it is read and compares it to the expected sum at the end.
+type verifyingReader struct {
+	io.Reader
+	io.Closer
+	hash hash.Hash
+	want []byte
+}
+
+func (v *verifyingReader) Read(p []byte) (int, error) {
+	n, err := v.Reader.Read(p)
+	v.hash.Write(p[:n])
+	if err == io.EOF && v.want != nil {
+		if got := v.hash.Sum(nil); !bytes.Equal(got, v.want) {
+			return n, fmt.Errorf("%w: expected md5-%s but got md5-%s", ErrDigestMismatch,
+				base64.StdEncoding.EncodeToString(v.want),
+				base64.StdEncoding.EncodeToString(got),
+			)
+		}
+	}
+	return n, err
+}
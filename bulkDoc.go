@@ -1,9 +1,104 @@
 package couchdb
 
+import "fmt"
+
 // BulkDoc describes POST /db/_bulk_docs request object.
 // http://docs.couchdb.org/en/latest/api/database/bulk-api.html#post--db-_bulk_docs
 type BulkDoc struct {
 	AllOrNothing bool       `json:"all_or_nothing,omitempty"`
-	NewEdits     bool       `json:"new_edits,omitempty"`
+	NewEdits     *bool      `json:"new_edits,omitempty"`
 	Docs         []CouchDoc `json:"docs"`
 }
+
+// BulkOptions are the optional parameters of a bulk request.
+type BulkOptions struct {
+	// AllOrNothing makes CouchDB 1.x write either all documents or none of them.
+	// It is not supported by CouchDB 2.x and later.
+	AllOrNothing bool
+	// NewEdits set to false stores the documents with their given revisions
+	// instead of creating new ones, e.g. to restore backups or write replicated revisions.
+	NewEdits *bool
+}
+
+// BulkResult is the outcome of a bulk request for a single document.
+// Error and Reason are set when the document could not be written,
+// e.g. because of a conflict or a failed validation.
+type BulkResult struct {
+	DocumentResponse
+	Error  string `json:"error,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Failed reports whether the document could not be written.
+func (r BulkResult) Failed() bool {
+	return r.Error != ""
+}
+
+// BulkResults is response from POST request to the _bulk_docs URL.
+type BulkResults []BulkResult
+
+// Succeeded returns the results of all documents that were written.
+func (r BulkResults) Succeeded() BulkResults {
+	succeeded := BulkResults{}
+	for _, result := range r {
+		if !result.Failed() {
+			succeeded = append(succeeded, result)
+		}
+	}
+	return succeeded
+}
+
+// Failed returns the results of all documents that could not be written.
+func (r BulkResults) Failed() BulkResults {
+	failed := BulkResults{}
+	for _, result := range r {
+		if result.Failed() {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// Err returns a *BulkError for all failed documents or nil if all documents were written.
+func (r BulkResults) Err() error {
+	failed := r.Failed()
+	if len(failed) == 0 {
+		return nil
+	}
+	return &BulkError{Failed: failed}
+}
+
+// BulkError describes the documents of a bulk request that could not be written.
+// errors.Is(err, ErrConflict) reports whether any of them failed because of a conflict.
+type BulkError struct {
+	Failed BulkResults
+}
+
+func (e *BulkError) Error() string {
+	if len(e.Failed) == 0 {
+		return "CouchDB - 0 documents failed in bulk request"
+	}
+	first := e.Failed[0]
+	return fmt.Sprintf(
+		"CouchDB - %d documents failed in bulk request, first ID: %s, Error: %s, Reason: %s",
+		len(e.Failed),
+		first.ID,
+		first.Error,
+		first.Reason,
+	)
+}
+
+// HasConflict reports whether any of the documents failed because of a conflict.
+func (e *BulkError) HasConflict() bool {
+	for _, result := range e.Failed {
+		if result.Error == "conflict" {
+			return true
+		}
+	}
+	return false
+}
+
+// Is makes errors.Is(err, ErrConflict) work with *BulkError.
+func (e *BulkError) Is(target error) bool {
+	return target == ErrConflict && e.HasConflict()
+}
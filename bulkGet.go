@@ -0,0 +1,288 @@
+package couchdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// BulkGetRef identifies a document for BulkGet.
+// The latest revision is returned when Rev is empty.
+type BulkGetRef struct {
+	ID  string `json:"id"`
+	Rev string `json:"rev,omitempty"`
+}
+
+// BulkGetOptions are the optional parameters of a _bulk_get request.
+type BulkGetOptions struct {
+	// Revs includes the revision history of every document.
+	Revs bool
+	// Attachments includes the content of attachments instead of stubs.
+	Attachments bool
+	// Latest returns the latest leaf revision instead of the requested one if it was updated.
+	Latest bool
+}
+
+// BulkGetResult is a single document returned by BulkGet.
+// Error is set when the document or revision could not be loaded.
+type BulkGetResult struct {
+	ID    string
+	Rev   string
+	Doc   json.RawMessage
+	Error *Error
+}
+
+// Decode decodes the document into doc or returns the error of the result.
+func (r BulkGetResult) Decode(doc CouchDoc) error {
+	if r.Error != nil {
+		return r.Error
+	}
+	return json.Unmarshal(r.Doc, decodeTarget(doc))
+}
+
+// BulkGet fetches many documents or revisions with a single request.
+// The results are in the order of refs.
+//
+//	results, err := db.BulkGet([]couchdb.BulkGetRef{{ID: "a"}, {ID: "b"}}, couchdb.BulkGetOptions{})
+//	for _, result := range results {
+//		player := &Player{}
+//		if err := result.Decode(player); err != nil {
+//			// document is missing
+//		}
+//	}
+//
+// CouchDB 1.6 has no _bulk_get. The documents are loaded from _all_docs
+// or one by one when revisions are requested.
+func (db *Database) BulkGet(refs []BulkGetRef, opts BulkGetOptions) ([]BulkGetResult, error) {
+	return db.BulkGetContext(context.Background(), refs, opts)
+}
+
+// BulkGetContext is like BulkGet but includes a context.
+func (db *Database) BulkGetContext(ctx context.Context, refs []BulkGetRef, opts BulkGetOptions) ([]BulkGetResult, error) {
+	results, err := db.bulkGet(ctx, refs, opts)
+	if missingEndpoint(err) {
+		return db.bulkGetFallback(ctx, refs, opts)
+	}
+	return results, err
+}
+
+// missingEndpoint reports whether err was caused by a server that does not know the endpoint,
+// e.g. CouchDB 1.6 answering requests to _bulk_get or multiple queries.
+func missingEndpoint(err error) bool {
+	var e *Error
+	if !errors.As(err, &e) {
+		return false
+	}
+	// a bad request is a real error since CouchDB 2, falling back would hide it
+	return e.StatusCode == http.StatusNotFound || e.StatusCode == http.StatusMethodNotAllowed
+}
+
+func (db *Database) bulkGet(ctx context.Context, refs []BulkGetRef, opts BulkGetOptions) ([]BulkGetResult, error) {
+	content := struct {
+		Docs []BulkGetRef `json:"docs"`
+	}{
+		Docs: refs,
+	}
+	var b bytes.Buffer
+	if err := json.NewEncoder(&b).Encode(content); err != nil {
+		return nil, err
+	}
+	u := fmt.Sprintf("%s/_bulk_get?%s", url.PathEscape(db.Name), opts.values(BulkGetRef{}).Encode())
+	res, err := db.Client.RequestContext(idempotent(ctx), http.MethodPost, u, &b, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	var response struct {
+		Results []struct {
+			ID   string `json:"id"`
+			Docs []struct {
+				OK    json.RawMessage `json:"ok"`
+				Error *struct {
+					ID     string `json:"id"`
+					Rev    string `json:"rev"`
+					Error  string `json:"error"`
+					Reason string `json:"reason"`
+				} `json:"error"`
+			} `json:"docs"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return nil, err
+	}
+	results := []BulkGetResult{}
+	for _, r := range response.Results {
+		for _, doc := range r.Docs {
+			result := BulkGetResult{ID: r.ID}
+			if doc.Error != nil {
+				result.Rev = doc.Error.Rev
+				result.Error = docError(http.MethodPost, u, doc.Error.Error, doc.Error.Reason)
+			} else {
+				result.Doc = doc.OK
+				result.Rev = docRev(doc.OK)
+			}
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+// bulkGetFallback loads documents without revision from _all_docs
+// and all other documents one by one.
+func (db *Database) bulkGetFallback(ctx context.Context, refs []BulkGetRef, opts BulkGetOptions) ([]BulkGetResult, error) {
+	results := make([]BulkGetResult, len(refs))
+	var keys []string
+	var indexes []int
+	for i, ref := range refs {
+		if ref.Rev == "" && !opts.Revs {
+			keys = append(keys, ref.ID)
+			indexes = append(indexes, i)
+			continue
+		}
+		result, err := db.bulkGetDoc(ctx, ref, opts)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = result
+	}
+	if len(keys) == 0 {
+		return results, nil
+	}
+	rows, err := db.bulkGetAllDocs(ctx, keys, opts)
+	if err != nil {
+		return nil, err
+	}
+	for i, row := range rows {
+		results[indexes[i]] = row
+	}
+	return results, nil
+}
+
+// bulkGetDoc loads a single document with GET /db/doc.
+func (db *Database) bulkGetDoc(ctx context.Context, ref BulkGetRef, opts BulkGetOptions) (BulkGetResult, error) {
+	u := fmt.Sprintf("%s/%s?%s", url.PathEscape(db.Name), url.PathEscape(ref.ID), opts.values(ref).Encode())
+	result := BulkGetResult{ID: ref.ID, Rev: ref.Rev}
+	res, err := db.Client.RequestContext(ctx, http.MethodGet, u, nil, "application/json")
+	if err != nil {
+		var e *Error
+		if errors.As(err, &e) && e.StatusCode == http.StatusNotFound {
+			result.Error = e
+			return result, nil
+		}
+		return result, err
+	}
+	defer res.Body.Close()
+	if err := json.NewDecoder(res.Body).Decode(&result.Doc); err != nil {
+		return result, err
+	}
+	result.Rev = docRev(result.Doc)
+	return result, nil
+}
+
+// bulkGetAllDocs loads the latest revisions of the documents from _all_docs.
+func (db *Database) bulkGetAllDocs(ctx context.Context, keys []string, opts BulkGetOptions) ([]BulkGetResult, error) {
+	content := struct {
+		Keys []string `json:"keys"`
+	}{
+		Keys: keys,
+	}
+	var b bytes.Buffer
+	if err := json.NewEncoder(&b).Encode(content); err != nil {
+		return nil, err
+	}
+	q := url.Values{}
+	q.Set("include_docs", "true")
+	if opts.Attachments {
+		q.Set("attachments", "true")
+	}
+	u := fmt.Sprintf("%s/_all_docs?%s", url.PathEscape(db.Name), q.Encode())
+	res, err := db.Client.RequestContext(idempotent(ctx), http.MethodPost, u, &b, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	var response struct {
+		Rows []struct {
+			ID    string `json:"id"`
+			Key   string `json:"key"`
+			Value struct {
+				Rev     string `json:"rev"`
+				Deleted bool   `json:"deleted"`
+			} `json:"value"`
+			Doc   json.RawMessage `json:"doc"`
+			Error string          `json:"error"`
+		} `json:"rows"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return nil, err
+	}
+	if len(response.Rows) != len(keys) {
+		return nil, fmt.Errorf("couchdb: expected %d rows from _all_docs but got %d", len(keys), len(response.Rows))
+	}
+	results := make([]BulkGetResult, len(keys))
+	for i, row := range response.Rows {
+		results[i] = BulkGetResult{ID: row.Key, Rev: row.Value.Rev}
+		switch {
+		case row.Error != "":
+			results[i].Error = docError(http.MethodPost, u, row.Error, "missing")
+		case row.Value.Deleted || len(row.Doc) == 0 || string(row.Doc) == "null":
+			results[i].Error = docError(http.MethodPost, u, "not_found", "deleted")
+		default:
+			results[i].Doc = row.Doc
+		}
+	}
+	return results, nil
+}
+
+// values returns the query parameters for loading the referenced document.
+func (opts BulkGetOptions) values(ref BulkGetRef) url.Values {
+	q := url.Values{}
+	if ref.Rev != "" {
+		q.Set("rev", ref.Rev)
+	}
+	if opts.Revs {
+		q.Set("revs", "true")
+	}
+	if opts.Attachments {
+		q.Set("attachments", "true")
+	}
+	if opts.Latest {
+		q.Set("latest", "true")
+	}
+	return q
+}
+
+// docError converts the error of a single document of a bulk response into an *Error
+// with the status code CouchDB would have returned for a single request.
+func docError(method, u, typ, reason string) *Error {
+	e := &Error{
+		Method: method,
+		URL:    u,
+		Type:   typ,
+		Reason: reason,
+	}
+	switch typ {
+	case "not_found":
+		e.StatusCode = http.StatusNotFound
+	case "unauthorized":
+		e.StatusCode = http.StatusUnauthorized
+	case "forbidden":
+		e.StatusCode = http.StatusForbidden
+	case "bad_request":
+		e.StatusCode = http.StatusBadRequest
+	}
+	return e
+}
+
+// docRev returns the _rev of an encoded document.
+func docRev(doc json.RawMessage) string {
+	var d struct {
+		Rev string `json:"_rev"`
+	}
+	json.Unmarshal(doc, &d)
+	return d.Rev
+}
@@ -0,0 +1,290 @@
+package couchdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrBulkWriterClosed is returned when writing to a closed BulkWriter.
+var ErrBulkWriterClosed = errors.New("couchdb: bulk writer closed")
+
+// BulkWriterOptions configures a BulkWriter.
+type BulkWriterOptions struct {
+	BulkOptions
+	// BatchSize is the maximum number of documents per request. Defaults to 1000.
+	BatchSize int
+	// BatchBytes is the maximum size of the encoded documents per request. Defaults to 4 MiB.
+	BatchBytes int
+	// Concurrency is the number of requests sent in parallel. Defaults to 4.
+	Concurrency int
+	// Retry describes how requests failing with transient errors are repeated.
+	// Defaults to DefaultRetryPolicy. Documents written by a request that failed
+	// halfway through are reported as conflicts by its retry.
+	// Batches with documents without id are not retried unless NewEdits is false,
+	// since CouchDB would store them again under new ids.
+	Retry RetryPolicy
+	// OnResult is called with the documents and results of every request.
+	// err is set when the whole request failed or the batch was not sent because the context was canceled.
+	OnResult func(docs []CouchDoc, results BulkResults, err error)
+	// OnProgress is called with the current statistics after every request.
+	OnProgress func(BulkStats)
+}
+
+// BulkStats are the statistics of a BulkWriter.
+type BulkStats struct {
+	// Written is the number of documents written successfully.
+	Written int
+	// Failed is the number of documents that could not be written.
+	Failed int
+	// Requests is the number of finished bulk requests.
+	Requests int
+	// Elapsed is the time since the writer was created.
+	Elapsed time.Duration
+}
+
+// DocsPerSecond returns the average number of written documents per second.
+func (s BulkStats) DocsPerSecond() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Written) / s.Elapsed.Seconds()
+}
+
+// BulkWriter imports large amounts of documents with concurrent _bulk_docs requests.
+// Documents are collected into batches limited by count and size.
+// OnResult and OnProgress are never called concurrently and may call Stats.
+// A slow callback delays the workers waiting to report their results.
+//
+//	w := couchdb.NewBulkWriter(ctx, db, couchdb.BulkWriterOptions{Concurrency: 8})
+//	for _, doc := range docs {
+//		if err := w.Write(doc); err != nil {
+//			return err
+//		}
+//	}
+//	stats, err := w.Close()
+type BulkWriter struct {
+	ctx     context.Context
+	db      DatabaseService
+	opts    BulkWriterOptions
+	start   time.Time
+	batches chan bulkBatch
+	wg      sync.WaitGroup
+
+	// writeMu guards the current batch
+	writeMu sync.Mutex
+	current bulkBatch
+	closed  bool
+
+	// callbackMu serializes the callbacks
+	callbackMu sync.Mutex
+
+	mu    sync.Mutex
+	stats BulkStats
+	err   error
+}
+
+type bulkBatch struct {
+	docs []CouchDoc
+	raw  []CouchDoc
+	size int
+	// anonymous is set when a document has no id and gets a new one from CouchDB
+	anonymous bool
+}
+
+// rawDoc is an already encoded document.
+type rawDoc struct {
+	id   string
+	rev  string
+	data json.RawMessage
+}
+
+func (d rawDoc) GetID() string {
+	return d.id
+}
+
+func (d rawDoc) GetRev() string {
+	return d.rev
+}
+
+func (d rawDoc) MarshalJSON() ([]byte, error) {
+	return d.data, nil
+}
+
+// NewBulkWriter returns a BulkWriter for the database.
+// Canceling the context aborts all pending requests.
+func NewBulkWriter(ctx context.Context, db DatabaseService, opts BulkWriterOptions) *BulkWriter {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 1000
+	}
+	if opts.BatchBytes <= 0 {
+		opts.BatchBytes = 4 << 20
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 4
+	}
+	if opts.Retry.MaxAttempts <= 0 {
+		opts.Retry = DefaultRetryPolicy
+	}
+	w := &BulkWriter{
+		ctx:     ctx,
+		db:      db,
+		opts:    opts,
+		start:   time.Now(),
+		batches: make(chan bulkBatch),
+	}
+	w.wg.Add(opts.Concurrency)
+	for i := 0; i < opts.Concurrency; i++ {
+		go w.work()
+	}
+	return w
+}
+
+// Write adds a document to the current batch and sends the batch when it is full.
+// It blocks while all requests are in flight.
+func (w *BulkWriter) Write(doc CouchDoc) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	w.writeMu.Lock()
+	defer w.writeMu.Unlock()
+	if w.closed {
+		return ErrBulkWriterClosed
+	}
+	if len(w.current.docs) > 0 && w.current.size+len(data) > w.opts.BatchBytes {
+		if err := w.flush(); err != nil {
+			return err
+		}
+	}
+	w.current.docs = append(w.current.docs, doc)
+	w.current.raw = append(w.current.raw, rawDoc{id: doc.GetID(), rev: doc.GetRev(), data: data})
+	w.current.size += len(data)
+	w.current.anonymous = w.current.anonymous || doc.GetID() == ""
+	if len(w.current.docs) >= w.opts.BatchSize {
+		return w.flush()
+	}
+	return nil
+}
+
+// WriteFrom writes all documents from the channel until it is closed.
+func (w *BulkWriter) WriteFrom(docs <-chan CouchDoc) error {
+	for doc := range docs {
+		if err := w.Write(doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush sends the current batch even if it is not full.
+func (w *BulkWriter) Flush() error {
+	w.writeMu.Lock()
+	defer w.writeMu.Unlock()
+	if w.closed {
+		return ErrBulkWriterClosed
+	}
+	return w.flush()
+}
+
+// Close sends the remaining documents and waits for all requests to finish.
+// It returns the final statistics and the first error of a failed request.
+func (w *BulkWriter) Close() (BulkStats, error) {
+	w.writeMu.Lock()
+	var err error
+	if !w.closed {
+		w.closed = true
+		err = w.flush()
+		close(w.batches)
+	}
+	w.writeMu.Unlock()
+	w.wg.Wait()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		err = w.err
+	}
+	return w.stats, err
+}
+
+// Stats returns the current statistics.
+func (w *BulkWriter) Stats() BulkStats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	stats := w.stats
+	stats.Elapsed = time.Since(w.start)
+	return stats
+}
+
+func (w *BulkWriter) flush() error {
+	if len(w.current.docs) == 0 {
+		return nil
+	}
+	b := w.current
+	w.current = bulkBatch{}
+	select {
+	case w.batches <- b:
+		return nil
+	case <-w.ctx.Done():
+		// the batch is never sent, report its documents as failed instead of losing them
+		w.report(b, nil, w.ctx.Err(), false)
+		return w.ctx.Err()
+	}
+}
+
+func (w *BulkWriter) work() {
+	defer w.wg.Done()
+	for b := range w.batches {
+		results, err := w.write(b)
+		w.report(b, results, err, true)
+	}
+}
+
+func (w *BulkWriter) write(b bulkBatch) (BulkResults, error) {
+	// a retry after a lost response would store documents without id a second time
+	// under new ids, only replicated revisions are safe to write again
+	retry := !b.anonymous || (w.opts.NewEdits != nil && !*w.opts.NewEdits)
+	for attempt := 1; ; attempt++ {
+		results, err := w.db.BulkWithOptionsContext(w.ctx, b.raw, w.opts.BulkOptions)
+		if err == nil || !retry || attempt >= w.opts.Retry.MaxAttempts || w.ctx.Err() != nil || !isTransient(err) {
+			return results, err
+		}
+		if err := sleep(w.ctx, w.opts.Retry.backoff(attempt, nil)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// report counts the results of a batch and calls the callbacks.
+// sent is false for batches dropped before their request.
+func (w *BulkWriter) report(b bulkBatch, results BulkResults, err error, sent bool) {
+	// callbacks are serialized and see the statistics in order,
+	// but w.mu is released so they can call Stats
+	w.callbackMu.Lock()
+	defer w.callbackMu.Unlock()
+	w.mu.Lock()
+	if sent {
+		w.stats.Requests++
+	}
+	if err != nil {
+		w.stats.Failed += len(b.docs)
+		if w.err == nil {
+			w.err = err
+		}
+	} else {
+		failed := len(results.Failed())
+		w.stats.Failed += failed
+		w.stats.Written += len(b.docs) - failed
+	}
+	w.stats.Elapsed = time.Since(w.start)
+	stats := w.stats
+	w.mu.Unlock()
+	if w.opts.OnResult != nil {
+		w.opts.OnResult(b.docs, results, err)
+	}
+	if w.opts.OnProgress != nil {
+		w.opts.OnProgress(stats)
+	}
+}
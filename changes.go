@@ -3,6 +3,7 @@ package couchdb
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -15,7 +16,11 @@ import (
 //   http://docs.couchdb.org/en/2.0.0/api/database/changes.html
 type ChangesService interface {
 	Stream(params ChangesQueryParameters) (<-chan Change, error)
+	StreamContext(ctx context.Context, params ChangesQueryParameters) (<-chan Change, error)
 	Poll(params ChangesQueryParameters) (*ChangesResponse, error)
+	PollContext(ctx context.Context, params ChangesQueryParameters) (*ChangesResponse, error)
+	Feed(params ChangesQueryParameters, opts FeedOptions) (*Feed, error)
+	FeedContext(ctx context.Context, params ChangesQueryParameters, opts FeedOptions) (*Feed, error)
 }
 
 // Changes performs actions and certain view documents
@@ -38,7 +43,7 @@ type Changes struct {
 //     200 OK – Request completed successfully
 //     400 Bad Request – Bad request
 type ChangesResponse struct {
-	LastSeq string   `json:"last_seq,omitempty"`
+	LastSeq Seq      `json:"last_seq,omitempty"`
 	Results []Change `json:"results,omitempty"`
 	Pending int      `json:"pending,omitempty"`
 }
@@ -47,7 +52,10 @@ type ChangesResponse struct {
 type Change struct {
 	Changes []Rev  `json:"changes"`
 	ID      string `json:"id"`
-	Seq     string `json:"seq,omitempty"`
+	Seq     Seq    `json:"seq,omitempty"`
+	Deleted bool   `json:"deleted,omitempty"`
+	// Doc is the changed document when requested with IncludeDocs.
+	Doc json.RawMessage `json:"doc,omitempty"`
 }
 
 // Rev hold the rev of the document changed.
@@ -68,14 +76,24 @@ type ChangesQueryParameters struct {
 	AttEncodingInfo *bool    `url:"att_encoding_info,omitempty"`
 	LastEventID     *int     `url:"last-event-id,omitempty"`
 	Limit           *int     `url:"limit,omitempty"`
-	Since           *string  `url:"since,omitempty"`
+	Since           *Seq     `url:"since,omitempty"`
 	Style           *string  `url:"style,omitempty"`
 	Timeout         *int     `url:"timeout,omitempty"`
 	View            *string  `url:"view,omitempty"`
 }
 
-// Stream reads continuously from a changes stream
+// Stream reads continuously from a changes stream.
+// The channel is closed as soon as the connection drops.
+// Lines that cannot be decoded are skipped without notice.
+// Use Feed for a stream that reconnects automatically and reports errors,
+// including undecodable lines through FeedOptions.OnError.
 func (c *Changes) Stream(params ChangesQueryParameters) (<-chan Change, error) {
+	return c.StreamContext(context.Background(), params)
+}
+
+// StreamContext is like Stream but includes a context.
+// Canceling the context closes the underlying connection and the returned channel.
+func (c *Changes) StreamContext(ctx context.Context, params ChangesQueryParameters) (<-chan Change, error) {
 	continuous := "continuous"
 	params.Feed = &continuous // Must be continuous for streaming
 	q, err := query.Values(params)
@@ -83,14 +101,17 @@ func (c *Changes) Stream(params ChangesQueryParameters) (<-chan Change, error) {
 		return nil, err
 	}
 	uri := fmt.Sprintf("%s/_changes?%s", url.PathEscape(c.Database.Name), q.Encode())
-	res, err := c.Database.Client.Request(http.MethodGet, uri, nil, "")
+	res, err := c.Database.Client.RequestContext(ctx, http.MethodGet, uri, nil, "")
+	if err != nil {
+		return nil, err
+	}
 
 	resultsChan := make(chan Change)
-	go readStream(res, resultsChan)
+	go readStream(ctx, res, resultsChan)
 
 	return resultsChan, nil
 }
-func readStream(r *http.Response, results chan Change) {
+func readStream(ctx context.Context, r *http.Response, results chan Change) {
 	defer r.Body.Close()
 	defer close(results)
 
@@ -105,7 +126,11 @@ func readStream(r *http.Response, results chan Change) {
 		var result Change
 		err = json.Unmarshal(line, &result)
 		if err == nil && result.Seq != "" {
-			results <- result
+			select {
+			case results <- result:
+			case <-ctx.Done():
+				return
+			}
 		}
 	}
 }
@@ -113,6 +138,11 @@ func readStream(r *http.Response, results chan Change) {
 // Poll requests from the database
 // This could be a long poll, where the Database blocks until there is new change
 func (c *Changes) Poll(params ChangesQueryParameters) (*ChangesResponse, error) {
+	return c.PollContext(context.Background(), params)
+}
+
+// PollContext is like Poll but includes a context.
+func (c *Changes) PollContext(ctx context.Context, params ChangesQueryParameters) (*ChangesResponse, error) {
 	// if Feed is set to continuous the server will stream instead of poll, unset it.
 	if params.Feed != nil && *(params.Feed) == "continuous" {
 		params.Feed = nil
@@ -122,7 +152,10 @@ func (c *Changes) Poll(params ChangesQueryParameters) (*ChangesResponse, error)
 		return nil, err
 	}
 	uri := fmt.Sprintf("%s/_changes?%s", url.PathEscape(c.Database.Name), q.Encode())
-	r, err := c.Database.Client.Request(http.MethodGet, uri, nil, "")
+	r, err := c.Database.Client.RequestContext(ctx, http.MethodGet, uri, nil, "")
+	if err != nil {
+		return nil, err
+	}
 	defer r.Body.Close()
 
 	response := ChangesResponse{}
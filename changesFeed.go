@@ -0,0 +1,248 @@
+package couchdb
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/go-querystring/query"
+)
+
+// ErrHeartbeatTimeout is reported when a continuous changes feed stays silent
+// for longer than twice the heartbeat interval.
+var ErrHeartbeatTimeout = errors.New("couchdb: no heartbeat from changes feed")
+
+// FeedOptions configures a continuous changes feed.
+type FeedOptions struct {
+	// BufferSize is the number of changes kept for a slow consumer.
+	// The feed stops reading from the connection while the buffer is full.
+	BufferSize int
+	// Heartbeat is the interval at which CouchDB sends empty lines on an idle connection.
+	// The connection is considered dead when nothing arrives for twice the interval.
+	// Defaults to 30 seconds.
+	Heartbeat time.Duration
+	// MaxRetries is the number of consecutive failed connection attempts after which the feed gives up.
+	// Zero means the feed reconnects forever.
+	MaxRetries int
+	// MinBackoff and MaxBackoff limit the delay between two connection attempts.
+	// They default to 100 milliseconds and 30 seconds.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// OnError is called for every error the feed recovers from,
+	// like a dropped connection or a line that could not be decoded.
+	OnError func(error)
+}
+
+// Feed is a continuous changes feed that reconnects automatically
+// and resumes from the sequence of the last delivered change.
+type Feed struct {
+	changes chan Change
+	cancel  context.CancelFunc
+	done    chan struct{}
+
+	mu      sync.Mutex
+	err     error
+	lastSeq Seq
+}
+
+// Feed starts a continuous changes feed.
+func (c *Changes) Feed(params ChangesQueryParameters, opts FeedOptions) (*Feed, error) {
+	return c.FeedContext(context.Background(), params, opts)
+}
+
+// FeedContext is like Feed but includes a context.
+// Canceling the context stops the feed and closes its channel.
+func (c *Changes) FeedContext(ctx context.Context, params ChangesQueryParameters, opts FeedOptions) (*Feed, error) {
+	continuous := "continuous"
+	params.Feed = &continuous
+	if opts.Heartbeat <= 0 {
+		opts.Heartbeat = 30 * time.Second
+	}
+	heartbeat := int(opts.Heartbeat / time.Millisecond)
+	params.Heartbeat = &heartbeat
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = 100 * time.Millisecond
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = 30 * time.Second
+	}
+	if opts.OnError == nil {
+		opts.OnError = func(error) {}
+	}
+	// fail early on invalid parameters instead of retrying forever
+	if _, err := query.Values(params); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	f := &Feed{
+		changes: make(chan Change, opts.BufferSize),
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	if params.Since != nil {
+		f.lastSeq = *params.Since
+	}
+	go f.run(ctx, c, params, opts)
+	return f, nil
+}
+
+// Changes returns the channel of changes. It is closed when the feed stops.
+func (f *Feed) Changes() <-chan Change {
+	return f.changes
+}
+
+// Err returns the error that stopped the feed.
+// It is nil as long as the feed is running and after Close.
+func (f *Feed) Err() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.err
+}
+
+// LastSeq returns the sequence of the last delivered change.
+// Persist it and pass it as Since to resume the feed later on.
+func (f *Feed) LastSeq() Seq {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.lastSeq
+}
+
+// Close stops the feed and waits until its connection is closed.
+func (f *Feed) Close() {
+	f.cancel()
+	<-f.done
+	f.mu.Lock()
+	f.err = nil
+	f.mu.Unlock()
+}
+
+func (f *Feed) run(ctx context.Context, c *Changes, params ChangesQueryParameters, opts FeedOptions) {
+	defer close(f.done)
+	defer close(f.changes)
+	backoff := RetryPolicy{MinBackoff: opts.MinBackoff, MaxBackoff: opts.MaxBackoff}
+	failures := 0
+	for {
+		progress, err := f.connect(ctx, c, params, opts)
+		if ctx.Err() != nil {
+			f.stop(ctx.Err())
+			return
+		}
+		if progress {
+			failures = 0
+		}
+		if err == nil {
+			continue
+		}
+		var cerr *Error
+		if errors.As(err, &cerr) && cerr.StatusCode >= 400 && cerr.StatusCode < 500 &&
+			cerr.StatusCode != http.StatusRequestTimeout && cerr.StatusCode != http.StatusTooManyRequests {
+			// client errors like a missing database will not go away by reconnecting
+			f.stop(err)
+			return
+		}
+		opts.OnError(err)
+		failures++
+		if opts.MaxRetries > 0 && failures >= opts.MaxRetries {
+			f.stop(err)
+			return
+		}
+		if err := sleep(ctx, backoff.backoff(failures, nil)); err != nil {
+			f.stop(err)
+			return
+		}
+	}
+}
+
+func (f *Feed) stop(err error) {
+	f.mu.Lock()
+	f.err = err
+	f.mu.Unlock()
+}
+
+// connect reads from a single connection until it breaks.
+// It reports whether any data arrived and returns nil when the server ended the feed regularly.
+func (f *Feed) connect(ctx context.Context, c *Changes, params ChangesQueryParameters, opts FeedOptions) (bool, error) {
+	if seq := f.LastSeq(); seq != "" {
+		params.Since = &seq
+	}
+	q, err := query.Values(params)
+	if err != nil {
+		return false, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	// abort the connection when neither changes nor heartbeats arrive in time
+	var timedOut int32
+	timeout := 2 * opts.Heartbeat
+	timer := time.AfterFunc(timeout, func() {
+		atomic.StoreInt32(&timedOut, 1)
+		cancel()
+	})
+	defer timer.Stop()
+	uri := fmt.Sprintf("%s/_changes?%s", url.PathEscape(c.Database.Name), q.Encode())
+	res, err := c.Database.Client.RequestContext(ctx, http.MethodGet, uri, nil, "")
+	if err != nil {
+		if atomic.LoadInt32(&timedOut) == 1 {
+			return false, ErrHeartbeatTimeout
+		}
+		return false, err
+	}
+	defer res.Body.Close()
+	progress := false
+	reader := bufio.NewReader(res.Body)
+	for {
+		line, err := reader.ReadBytes('\n')
+		timer.Stop()
+		if atomic.LoadInt32(&timedOut) == 1 {
+			return progress, ErrHeartbeatTimeout
+		}
+		if len(line) > 0 {
+			progress = true
+		}
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			var result struct {
+				Change
+				LastSeq Seq `json:"last_seq"`
+			}
+			if err := json.Unmarshal(line, &result); err != nil {
+				opts.OnError(fmt.Errorf("couchdb: decoding change %q: %w", line, err))
+			} else if result.LastSeq != "" {
+				// the server ended the feed, e.g. because of a timeout
+				f.setLastSeq(result.LastSeq)
+				return progress, nil
+			} else {
+				select {
+				case f.changes <- result.Change:
+					f.setLastSeq(result.Seq)
+				case <-ctx.Done():
+					return progress, ctx.Err()
+				}
+			}
+		}
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return progress, err
+		}
+		timer.Reset(timeout)
+	}
+}
+
+func (f *Feed) setLastSeq(seq Seq) {
+	if seq == "" {
+		return
+	}
+	f.mu.Lock()
+	f.lastSeq = seq
+	f.mu.Unlock()
+}
@@ -2,7 +2,9 @@ package couchdb
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -11,6 +13,7 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -20,41 +23,111 @@ type Client struct {
 	Password  string
 	BaseURL   *url.URL
 	CookieJar *cookiejar.Jar
+	// HTTPClient performs all requests. It is shared between requests
+	// so keep-alive connections to the server are reused.
+	HTTPClient *http.Client
+	// UserAgent is sent as User-Agent header when not empty.
+	UserAgent string
+	// Header holds default headers sent with every request.
+	Header http.Header
+	// Retry enables automatic retries of transient failures when not nil.
+	Retry *RetryPolicy
+	// ConflictRetries is the number of times Database.Update and Database.Upsert
+	// fetch the document again and reapply their changes after a conflict.
+	ConflictRetries int
+	// Timeout limits how long a request waits for the response headers when not zero.
+	// Reading the response body is not limited so streams and feeds can stay open.
+	Timeout time.Duration
 }
 
-// NewClient returns new couchdb client for given url
-func NewClient(u *url.URL) (*Client, error) {
-	jar, err := cookiejar.New(nil)
-	if err != nil {
-		return nil, err
+// Option configures a Client created by NewClient or NewAuthClient.
+type Option func(*Client)
+
+// WithHTTPClient makes the client use a copy of the given http.Client.
+// The cookie jar of the client is used when the given one has none.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		clone := *hc
+		if clone.Jar == nil {
+			clone.Jar = c.CookieJar
+		}
+		c.HTTPClient = &clone
 	}
-	c := &Client{
-		Username:  "",
-		Password:  "",
-		BaseURL:   u,
-		CookieJar: jar,
+}
+
+// WithTransport sets the http.RoundTripper used for all requests,
+// e.g. an *http.Transport with custom TLS config, proxy or pool settings.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(c *Client) {
+		c.HTTPClient.Transport = rt
+	}
+}
+
+// WithTimeout sets how long every request waits for the response headers.
+// Unlike http.Client.Timeout it does not limit reading the response body,
+// use a context to limit the whole request.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.Timeout = d
+	}
+}
+
+// WithConflictRetries sets how often Database.Update and Database.Upsert retry after a conflict.
+func WithConflictRetries(n int) Option {
+	return func(c *Client) {
+		c.ConflictRetries = n
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request.
+func WithUserAgent(ua string) Option {
+	return func(c *Client) {
+		c.UserAgent = ua
+	}
+}
+
+// WithHeader adds a default header sent with every request.
+func WithHeader(key, value string) Option {
+	return func(c *Client) {
+		c.Header.Add(key, value)
 	}
-	return c, nil
+}
+
+// NewClient returns new couchdb client for given url
+func NewClient(u *url.URL, opts ...Option) (*Client, error) {
+	return NewAuthClient("", "", u, opts...)
 }
 
 // NewAuthClient returns new couchdb client with basic authentication
-func NewAuthClient(username, password string, u *url.URL) (*Client, error) {
+func NewAuthClient(username, password string, u *url.URL, opts ...Option) (*Client, error) {
 	jar, err := cookiejar.New(nil)
 	if err != nil {
 		return nil, err
 	}
-	return &Client{
-		Username:  username,
-		Password:  password,
-		BaseURL:   u,
-		CookieJar: jar,
-	}, nil
+	c := &Client{
+		Username:        username,
+		Password:        password,
+		BaseURL:         u,
+		CookieJar:       jar,
+		HTTPClient:      &http.Client{Jar: jar},
+		Header:          http.Header{},
+		ConflictRetries: DefaultConflictRetries,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
 }
 
 // Info returns some information about the server
 func (c *Client) Info() (*Server, error) {
+	return c.InfoContext(context.Background())
+}
+
+// InfoContext is like Info but includes a context.
+func (c *Client) InfoContext(ctx context.Context) (*Server, error) {
 	u := ""
-	res, err := c.Request(http.MethodGet, u, nil, "application/json")
+	res, err := c.RequestContext(ctx, http.MethodGet, u, nil, "application/json")
 	if err != nil {
 		return nil, err
 	}
@@ -65,8 +138,13 @@ func (c *Client) Info() (*Server, error) {
 
 // ActiveTasks returns list of currently running tasks
 func (c *Client) ActiveTasks() ([]Task, error) {
+	return c.ActiveTasksContext(context.Background())
+}
+
+// ActiveTasksContext is like ActiveTasks but includes a context.
+func (c *Client) ActiveTasksContext(ctx context.Context) ([]Task, error) {
 	u := "_active_tasks"
-	res, err := c.Request(http.MethodGet, u, nil, "application/json")
+	res, err := c.RequestContext(ctx, http.MethodGet, u, nil, "application/json")
 	if err != nil {
 		return nil, err
 	}
@@ -77,8 +155,13 @@ func (c *Client) ActiveTasks() ([]Task, error) {
 
 // All returns list of all databases on server
 func (c *Client) All() ([]string, error) {
+	return c.AllContext(context.Background())
+}
+
+// AllContext is like All but includes a context.
+func (c *Client) AllContext(ctx context.Context) ([]string, error) {
 	u := "_all_dbs"
-	res, err := c.Request(http.MethodGet, u, nil, "application/json")
+	res, err := c.RequestContext(ctx, http.MethodGet, u, nil, "application/json")
 	if err != nil {
 		return nil, err
 	}
@@ -89,8 +172,13 @@ func (c *Client) All() ([]string, error) {
 
 // Get database.
 func (c *Client) Get(name string) (*DatabaseInfo, error) {
+	return c.GetContext(context.Background(), name)
+}
+
+// GetContext is like Get but includes a context.
+func (c *Client) GetContext(ctx context.Context, name string) (*DatabaseInfo, error) {
 	u := url.PathEscape(name)
-	res, err := c.Request(http.MethodGet, u, nil, "application/json")
+	res, err := c.RequestContext(ctx, http.MethodGet, u, nil, "application/json")
 	if err != nil {
 		return nil, err
 	}
@@ -101,8 +189,13 @@ func (c *Client) Get(name string) (*DatabaseInfo, error) {
 
 // Create database.
 func (c *Client) Create(name string) (*DatabaseResponse, error) {
+	return c.CreateContext(context.Background(), name)
+}
+
+// CreateContext is like Create but includes a context.
+func (c *Client) CreateContext(ctx context.Context, name string) (*DatabaseResponse, error) {
 	u := url.PathEscape(name)
-	res, err := c.Request(http.MethodPut, u, nil, "application/json")
+	res, err := c.RequestContext(ctx, http.MethodPut, u, nil, "application/json")
 	if err != nil {
 		return nil, err
 	}
@@ -113,8 +206,13 @@ func (c *Client) Create(name string) (*DatabaseResponse, error) {
 
 // Delete database.
 func (c *Client) Delete(name string) (*DatabaseResponse, error) {
+	return c.DeleteContext(context.Background(), name)
+}
+
+// DeleteContext is like Delete but includes a context.
+func (c *Client) DeleteContext(ctx context.Context, name string) (*DatabaseResponse, error) {
 	u := url.PathEscape(name)
-	res, err := c.Request(http.MethodDelete, u, nil, "application/json")
+	res, err := c.RequestContext(ctx, http.MethodDelete, u, nil, "application/json")
 	if err != nil {
 		return nil, err
 	}
@@ -125,12 +223,17 @@ func (c *Client) Delete(name string) (*DatabaseResponse, error) {
 
 // CreateUser creates a new user in _users database
 func (c *Client) CreateUser(user User) (*DocumentResponse, error) {
+	return c.CreateUserContext(context.Background(), user)
+}
+
+// CreateUserContext is like CreateUser but includes a context.
+func (c *Client) CreateUserContext(ctx context.Context, user User) (*DocumentResponse, error) {
 	u := fmt.Sprintf("_users/%s", user.ID)
 	var b bytes.Buffer
 	if err := json.NewEncoder(&b).Encode(user); err != nil {
 		return nil, err
 	}
-	res, err := c.Request(http.MethodPut, u, &b, "application/json")
+	res, err := c.RequestContext(ctx, http.MethodPut, u, &b, "application/json")
 	if err != nil {
 		return nil, err
 	}
@@ -141,8 +244,13 @@ func (c *Client) CreateUser(user User) (*DocumentResponse, error) {
 
 // GetUser returns user by given name
 func (c *Client) GetUser(name string) (*User, error) {
+	return c.GetUserContext(context.Background(), name)
+}
+
+// GetUserContext is like GetUser but includes a context.
+func (c *Client) GetUserContext(ctx context.Context, name string) (*User, error) {
 	u := fmt.Sprintf("_users/org.couchdb.user:%s", name)
-	res, err := c.Request(http.MethodGet, u, nil, "application/json")
+	res, err := c.RequestContext(ctx, http.MethodGet, u, nil, "application/json")
 	if err != nil {
 		return nil, err
 	}
@@ -153,19 +261,29 @@ func (c *Client) GetUser(name string) (*User, error) {
 
 // DeleteUser removes user from database
 func (c *Client) DeleteUser(user *User) (*DocumentResponse, error) {
+	return c.DeleteUserContext(context.Background(), user)
+}
+
+// DeleteUserContext is like DeleteUser but includes a context.
+func (c *Client) DeleteUserContext(ctx context.Context, user *User) (*DocumentResponse, error) {
 	db := c.Use("_users")
-	return db.Delete(user)
+	return db.DeleteContext(ctx, user)
 }
 
 // CreateSession creates a new session and logs in user
 func (c *Client) CreateSession(name, password string) (*PostSessionResponse, error) {
+	return c.CreateSessionContext(context.Background(), name, password)
+}
+
+// CreateSessionContext is like CreateSession but includes a context.
+func (c *Client) CreateSessionContext(ctx context.Context, name, password string) (*PostSessionResponse, error) {
 	u := "_session"
 	creds := Credentials{name, password}
 	var b bytes.Buffer
 	if err := json.NewEncoder(&b).Encode(creds); err != nil {
 		return nil, err
 	}
-	res, err := c.Request(http.MethodPost, u, &b, "application/json")
+	res, err := c.RequestContext(ctx, http.MethodPost, u, &b, "application/json")
 	if err != nil {
 		return nil, err
 	}
@@ -176,8 +294,13 @@ func (c *Client) CreateSession(name, password string) (*PostSessionResponse, err
 
 // GetSession returns session for currently logged in user
 func (c *Client) GetSession() (*GetSessionResponse, error) {
+	return c.GetSessionContext(context.Background())
+}
+
+// GetSessionContext is like GetSession but includes a context.
+func (c *Client) GetSessionContext(ctx context.Context) (*GetSessionResponse, error) {
 	u := "_session"
-	res, err := c.Request(http.MethodGet, u, nil, "")
+	res, err := c.RequestContext(ctx, http.MethodGet, u, nil, "")
 	if err != nil {
 		return nil, err
 	}
@@ -188,8 +311,13 @@ func (c *Client) GetSession() (*GetSessionResponse, error) {
 
 // DeleteSession removes current session and logs out user
 func (c *Client) DeleteSession() (*DatabaseResponse, error) {
+	return c.DeleteSessionContext(context.Background())
+}
+
+// DeleteSessionContext is like DeleteSession but includes a context.
+func (c *Client) DeleteSessionContext(ctx context.Context) (*DatabaseResponse, error) {
 	u := "_session"
-	res, err := c.Request(http.MethodDelete, u, nil, "")
+	res, err := c.RequestContext(ctx, http.MethodDelete, u, nil, "")
 	if err != nil {
 		return nil, err
 	}
@@ -309,11 +437,16 @@ type Replication struct {
 //
 // http://docs.couchdb.org/en/1.6.1/api/server/common.html#replicate
 func (c *Client) Replicate(req ReplicationRequest) (*ReplicationResponse, error) {
+	return c.ReplicateContext(context.Background(), req)
+}
+
+// ReplicateContext is like Replicate but includes a context.
+func (c *Client) ReplicateContext(ctx context.Context, req ReplicationRequest) (*ReplicationResponse, error) {
 	var b bytes.Buffer
 	if err := json.NewEncoder(&b).Encode(req); err != nil {
 		return nil, err
 	}
-	res, err := c.Request(http.MethodPost, "_replicate", &b, "application/json")
+	res, err := c.RequestContext(ctx, http.MethodPost, "_replicate", &b, "application/json")
 	if err != nil {
 		return nil, err
 	}
@@ -324,25 +457,127 @@ func (c *Client) Replicate(req ReplicationRequest) (*ReplicationResponse, error)
 
 // Request creates new http request and does it.
 func (c *Client) Request(method, uri string, data io.Reader, contentType string) (*http.Response, error) {
+	return c.RequestContext(context.Background(), method, uri, data, contentType)
+}
+
+// RequestContext is like Request but includes a context.
+// The context controls the entire lifetime of the request and its response:
+// canceling it aborts the in-flight HTTP call and closes the response body.
+// The returned error then wraps context.Canceled or context.DeadlineExceeded
+// and can be checked with errors.Is.
+func (c *Client) RequestContext(ctx context.Context, method, uri string, data io.Reader, contentType string) (*http.Response, error) {
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	return c.request(ctx, method, uri, data, header)
+}
+
+// request is like RequestContext but sends the given headers, e.g. Accept or Destination.
+// Bodies wrapped in a sizedReader are sent with a Content-Length instead of chunked.
+// ErrHeaderTimeout is reported when the response headers do not arrive within Client.Timeout.
+var ErrHeaderTimeout = errors.New("couchdb: timeout awaiting response headers")
+
+// do sends req and cancels it when the response headers do not arrive within c.Timeout.
+func (c *Client) do(client *http.Client, req *http.Request) (*http.Response, error) {
+	if c.Timeout <= 0 {
+		return client.Do(req)
+	}
+	ctx, cancel := context.WithCancel(req.Context())
+	timer := time.AfterFunc(c.Timeout, cancel)
+	res, err := client.Do(req.WithContext(ctx))
+	if !timer.Stop() {
+		// the timer fired and canceled the request
+		cancel()
+		if err == nil {
+			res.Body.Close()
+		}
+		return nil, &url.Error{Op: req.Method, URL: req.URL.String(), Err: ErrHeaderTimeout}
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	res.Body = &cancelBody{ReadCloser: res.Body, cancel: cancel}
+	return res, nil
+}
+
+// cancelBody releases the context of a request once its body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+func (c *Client) request(ctx context.Context, method, uri string, data io.Reader, header http.Header) (*http.Response, error) {
 	rel, err := url.Parse(uri)
 	if err != nil {
 		return nil, err
 	}
 	u := c.BaseURL.ResolveReference(rel)
-	req, err := http.NewRequest(method, u.String(), data)
+	retry := c.Retry != nil && isIdempotent(ctx, method)
+	if retry && data != nil {
+		// buffer bodies that cannot be rewound so a retry sends the same payload again
+		switch data.(type) {
+		case *bytes.Buffer, *bytes.Reader, *strings.Reader:
+		default:
+			b, err := ioutil.ReadAll(data)
+			if err != nil {
+				return nil, err
+			}
+			data = bytes.NewReader(b)
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), data)
 	if err != nil {
 		return nil, err
 	}
-	if contentType != "" {
-		req.Header.Set("Content-Type", contentType)
+	for key, values := range c.Header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if s, ok := data.(*sizedReader); ok {
+		req.ContentLength = s.size
 	}
 	// basic auth
 	if c.Username != "" && c.Password != "" {
 		req.SetBasicAuth(c.Username, c.Password)
 	}
 	// add cookies
-	client := &http.Client{Jar: c.CookieJar}
-	res, err := client.Do(req)
+	client := c.HTTPClient
+	if client == nil {
+		client = &http.Client{Jar: c.CookieJar}
+	}
+	res, err := c.do(client, req)
+	for attempt := 1; retry && attempt < c.Retry.MaxAttempts && ctx.Err() == nil && retryable(res, err); attempt++ {
+		wait := c.Retry.backoff(attempt, res)
+		if err == nil {
+			io.Copy(ioutil.Discard, res.Body)
+			res.Body.Close()
+		}
+		if err := sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+		req = req.Clone(ctx)
+		if req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+		res, err = c.do(client, req)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -351,7 +586,9 @@ func (c *Client) Request(method, uri string, data io.Reader, contentType string)
 		return nil, newError(res)
 	}
 	// save new cookies
-	c.CookieJar.SetCookies(req.URL, res.Cookies())
+	if c.CookieJar != nil {
+		c.CookieJar.SetCookies(req.URL, res.Cookies())
+	}
 	return res, nil
 }
 
@@ -2,16 +2,29 @@ package couchdb
 
 import (
 	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/google/go-querystring/query"
 	"github.com/segmentio/pointer"
 )
 
@@ -39,6 +52,17 @@ func TestMain(m *testing.M) {
 	os.Exit(code)
 }
 
+// skipCouchDB1 skips tests for features that are not available in CouchDB 1.x.
+func skipCouchDB1(t *testing.T) {
+	info, err := client.Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.HasPrefix(info.Version, "1.") {
+		t.Skipf("not supported by CouchDB %s", info.Version)
+	}
+}
+
 func TestInfo(t *testing.T) {
 	info, err := client.Info()
 	if err != nil {
@@ -397,6 +421,67 @@ func TestDeleteFail(t *testing.T) {
 	}
 }
 
+func TestErrorSentinels(t *testing.T) {
+	name, err := RandDBName(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Create(name); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Delete(name)
+	_, err = client.Create(name)
+	if !errors.Is(err, ErrFileExists) {
+		t.Errorf("expected ErrFileExists but got %v", err)
+	}
+	if !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("expected ErrPreconditionFailed but got %v", err)
+	}
+	db := client.Use(name)
+	if err := db.Get(&DummyDocument{}, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound but got %v", err)
+	}
+	if _, err := db.Head("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for HEAD request but got %v", err)
+	}
+	doc := &DummyDocument{Document: Document{ID: "conflict"}}
+	if _, err := db.Put(doc); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Put(doc); !errors.Is(err, ErrConflict) {
+		t.Errorf("expected ErrConflict but got %v", err)
+	}
+}
+
+func TestErrorWithoutJSON(t *testing.T) {
+	for _, body := range []string{"<html>Bad Gateway</html>", "null"} {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+			fmt.Fprint(w, body)
+		}))
+		u, err := url.Parse(ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := NewClient(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = c.Info()
+		ts.Close()
+		var cerr *Error
+		if !errors.As(err, &cerr) {
+			t.Fatalf("expected *Error but got %v", err)
+		}
+		if cerr.StatusCode != http.StatusBadGateway {
+			t.Errorf("expected status code 502 but got %d", cerr.StatusCode)
+		}
+		if string(cerr.Body) != body {
+			t.Errorf("expected raw body %q but got %q", body, cerr.Body)
+		}
+	}
+}
+
 type animal struct {
 	Document
 	Type   string `json:"type"`
@@ -605,6 +690,303 @@ func TestRequest(t *testing.T) {
 	}
 }
 
+func TestRequestContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.InfoContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled but got %v", err)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+	if _, err := client.Use("_users").AllDocsContext(ctx, &QueryParameters{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded but got %v", err)
+	}
+}
+
+type countingTransport struct {
+	requests int
+}
+
+func (ct *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ct.requests++
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestClientOptions(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ua := r.Header.Get("User-Agent"); ua != "couchdb-test" {
+			t.Errorf("expected user agent couchdb-test but got %s", ua)
+		}
+		if h := r.Header.Get("X-Custom"); h != "value" {
+			t.Errorf("expected X-Custom header to be value but got %s", h)
+		}
+		fmt.Fprint(w, `{"couchdb":"Welcome"}`)
+	}))
+	defer ts.Close()
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport := &countingTransport{}
+	c, err := NewClient(u,
+		WithTransport(transport),
+		WithTimeout(time.Second),
+		WithUserAgent("couchdb-test"),
+		WithHeader("X-Custom", "value"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := c.Info(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if transport.requests != 2 {
+		t.Errorf("expected 2 requests through custom transport but got %d", transport.requests)
+	}
+	if c.Timeout != time.Second || c.HTTPClient.Timeout != 0 {
+		t.Errorf("expected header timeout of 1s but got %s and %s", c.Timeout, c.HTTPClient.Timeout)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow-headers" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		// a slow body must not be cut off by the timeout
+		time.Sleep(200 * time.Millisecond)
+		fmt.Fprint(w, `{"couchdb":"Welcome"}`)
+	}))
+	defer ts.Close()
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewClient(u, WithTimeout(50*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := c.Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Couchdb != "Welcome" {
+		t.Errorf("expected complete body but got %+v", info)
+	}
+	if _, err := c.Request(http.MethodGet, "slow-headers", nil, "application/json"); !errors.Is(err, ErrHeaderTimeout) {
+		t.Errorf("expected ErrHeaderTimeout but got %v", err)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	var attempts int
+	var bodies []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		b, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		if attempts < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprint(w, `{"error":"unavailable","reason":"try again"}`)
+			return
+		}
+		fmt.Fprint(w, `{"ok":true,"id":"doc","rev":"2-abc"}`)
+	}))
+	defer ts.Close()
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewClient(u, WithRetry(RetryPolicy{MaxAttempts: 5, MinBackoff: time.Millisecond}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := c.Use("db")
+	// put with explicit revision is idempotent and must be retried with the same body
+	doc := &DummyDocument{Document: Document{ID: "doc", Rev: "1-abc"}, Foo: "bar"}
+	if _, err := db.Put(doc); err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts but got %d", attempts)
+	}
+	for _, b := range bodies {
+		if b != bodies[0] {
+			t.Errorf("expected retry to send %q but got %q", bodies[0], b)
+		}
+	}
+	// post is not idempotent and must not be retried
+	attempts = 0
+	if _, err := db.Post(doc); err == nil {
+		t.Error("expected post to fail")
+	}
+	if attempts != 1 {
+		t.Errorf("expected 1 attempt but got %d", attempts)
+	}
+}
+
+func TestChangesFeed(t *testing.T) {
+	var since []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		since = append(since, r.URL.Query().Get("since"))
+		if len(since) == 1 {
+			fmt.Fprintln(w, `{"seq":"1","id":"a","changes":[{"rev":"1-a"}]}`)
+			fmt.Fprintln(w, `not json`)
+			fmt.Fprintln(w, `{"seq":"2","id":"b","changes":[{"rev":"1-b"}]}`)
+			// drop connection
+			return
+		}
+		fmt.Fprintln(w, `{"seq":"3","id":"c","changes":[{"rev":"1-c"}]}`)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewClient(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var errs []error
+	feed, err := c.Use("db").Changes().Feed(ChangesQueryParameters{}, FeedOptions{
+		BufferSize: 1,
+		MinBackoff: time.Millisecond,
+		OnError: func(err error) {
+			errs = append(errs, err)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for change := range feed.Changes() {
+		ids = append(ids, change.ID)
+		if len(ids) == 3 {
+			feed.Close()
+		}
+	}
+	if !reflect.DeepEqual(ids, []string{"a", "b", "c"}) {
+		t.Errorf("expected changes a, b and c but got %v", ids)
+	}
+	if !reflect.DeepEqual(since, []string{"", "2"}) {
+		t.Errorf("expected feed to resume from seq 2 but got %v", since)
+	}
+	if len(errs) != 2 {
+		t.Errorf("expected decode and connection errors but got %v", errs)
+	}
+	if feed.Err() != nil {
+		t.Errorf("expected no error after close but got %v", feed.Err())
+	}
+	if feed.LastSeq() != "3" {
+		t.Errorf("expected last seq 3 but got %s", feed.LastSeq())
+	}
+}
+
+func TestChangesFeedMissingDatabase(t *testing.T) {
+	// fail instead of reconnecting forever when the server is unreachable
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	feed, err := client.Use("missing").Changes().FeedContext(ctx, ChangesQueryParameters{}, FeedOptions{MaxRetries: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range feed.Changes() {
+	}
+	if !errors.Is(feed.Err(), ErrNotFound) {
+		t.Errorf("expected ErrNotFound but got %v", feed.Err())
+	}
+	if _, err := client.Use("missing").Changes().Stream(ChangesQueryParameters{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound but got %v", err)
+	}
+}
+
+func TestChangesFeedMaxRetries(t *testing.T) {
+	var mu sync.Mutex
+	attempts := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		attempts++
+		mu.Unlock()
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, `{"error":"unknown_error","reason":"boom"}`)
+	}))
+	defer ts.Close()
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewClient(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed, err := c.Use("db").Changes().Feed(ChangesQueryParameters{}, FeedOptions{
+		MaxRetries: 3,
+		MinBackoff: time.Millisecond,
+		MaxBackoff: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range feed.Changes() {
+	}
+	var e *Error
+	if !errors.As(feed.Err(), &e) || e.StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected status 500 but got %v", feed.Err())
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts but got %d", attempts)
+	}
+}
+
+func TestSeq(t *testing.T) {
+	tests := []struct {
+		json string
+		seq  Seq
+	}{
+		{`{"seq":12,"id":"a","changes":[]}`, "12"},
+		{`{"seq":"12-g1AAAAEzeJzLYWBg","id":"a","changes":[]}`, "12-g1AAAAEzeJzLYWBg"},
+		{`{"seq":[12,"g1AAAAEz"],"id":"a","changes":[]}`, `[12,"g1AAAAEz"]`},
+	}
+	for _, test := range tests {
+		var change Change
+		if err := json.Unmarshal([]byte(test.json), &change); err != nil {
+			t.Fatal(err)
+		}
+		if change.Seq != test.seq {
+			t.Errorf("expected seq %s but got %s", test.seq, change.Seq)
+		}
+		b, err := json.Marshal(change)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var roundTrip Change
+		if err := json.Unmarshal(b, &roundTrip); err != nil {
+			t.Fatal(err)
+		}
+		if roundTrip.Seq != test.seq {
+			t.Errorf("expected seq %s after round trip but got %s", test.seq, roundTrip.Seq)
+		}
+	}
+	since := Seq("12-g1AAAAEzeJzLYWBg")
+	q, err := query.Values(ChangesQueryParameters{Since: &since})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Get("since") != "12-g1AAAAEzeJzLYWBg" {
+		t.Errorf("expected since to be 12-g1AAAAEzeJzLYWBg but got %s", q.Get("since"))
+	}
+}
+
 // database tests
 type DummyDocument struct {
 	Document
@@ -1207,7 +1589,7 @@ func TestView(t *testing.T) {
 	t.Run("get with query parameters", func(t *testing.T) {
 		view := db.View("test")
 		params := QueryParameters{
-			Key: pointer.String(fmt.Sprintf("%q", "foo1")),
+			Key: "foo1",
 		}
 		res, err := view.Get("foo", params)
 		if err != nil {
@@ -1221,8 +1603,8 @@ func TestView(t *testing.T) {
 	t.Run("get with start and end key", func(t *testing.T) {
 		view := db.View("test")
 		params := QueryParameters{
-			StartKey: pointer.String(fmt.Sprintf("[%q,%q]", "foo2", "beep2")),
-			EndKey:   pointer.String(fmt.Sprintf("[%q,%q]", "foo2", "beep2")),
+			StartKey: []string{"foo2", "beep2"},
+			EndKey:   []string{"foo2", "beep2"},
 		}
 		res, err := view.Get("complex", params)
 		if err != nil {
@@ -1236,8 +1618,8 @@ func TestView(t *testing.T) {
 	t.Run("get with integer", func(t *testing.T) {
 		view := db.View("test")
 		params := QueryParameters{
-			StartKey: pointer.String(fmt.Sprintf("[%q,%d]", "foo2", 20)),
-			EndKey:   pointer.String(fmt.Sprintf("[%q,%d]", "foo2", 20)),
+			StartKey: []interface{}{"foo2", 20},
+			EndKey:   []interface{}{"foo2", 20},
 		}
 		res, err := view.Get("int", params)
 		if err != nil {
@@ -1264,7 +1646,7 @@ func TestView(t *testing.T) {
 	t.Run("get with reduce and group", func(t *testing.T) {
 		view := db.View("person")
 		params := QueryParameters{
-			Key:        pointer.String(fmt.Sprintf("%q", "female")),
+			Key:        "female",
 			GroupLevel: pointer.Int(1),
 		}
 		res, err := view.Get("ageByGender", params)
@@ -1280,7 +1662,7 @@ func TestView(t *testing.T) {
 	t.Run("get without reduce", func(t *testing.T) {
 		view := db.View("person")
 		params := QueryParameters{
-			Key:    pointer.String(fmt.Sprintf("%q", "male")),
+			Key:    "male",
 			Reduce: pointer.Bool(false),
 		}
 		res, err := view.Get("ageByGender", params)
@@ -1460,6 +1842,82 @@ func TestDiff(t *testing.T) {
 			changes:   1,
 			deletions: 0,
 		},
+		{
+			desc: "update handler changed",
+			cache: []DesignDocument{
+				{
+					Document: Document{
+						ID: "_design/player",
+					},
+					Updates: map[string]string{
+						"increment": "function(doc, req) { return [doc, 'ok'] }",
+					},
+					ValidateDocUpdate: "function(newDoc) {}",
+				},
+			},
+			database: []DesignDocument{
+				{
+					Document: Document{
+						ID:  "_design/player",
+						Rev: "abc",
+					},
+					Updates: map[string]string{
+						"increment": "function(doc, req) { return [null, 'ok'] }",
+					},
+					ValidateDocUpdate: "function(newDoc) {}",
+				},
+			},
+			additions: 0,
+			changes:   1,
+			deletions: 0,
+		},
+		{
+			desc: "validate function changed",
+			cache: []DesignDocument{
+				{
+					Document: Document{
+						ID: "_design/player",
+					},
+					ValidateDocUpdate: "function(newDoc) { throw({forbidden: 'no'}) }",
+				},
+			},
+			database: []DesignDocument{
+				{
+					Document: Document{
+						ID:  "_design/player",
+						Rev: "abc",
+					},
+					ValidateDocUpdate: "function(newDoc) {}",
+				},
+			},
+			additions: 0,
+			changes:   1,
+			deletions: 0,
+		},
+		{
+			desc: "decoded rewrites are equal",
+			cache: []DesignDocument{
+				{
+					Document: Document{
+						ID: "_design/player",
+					},
+					Shows:    map[string]string{},
+					Rewrites: []DesignDocumentRewrite{{From: "/", To: "_show/html", Method: "GET", Query: map[string]interface{}{"format": "html"}}},
+				},
+			},
+			database: []DesignDocument{
+				{
+					Document: Document{
+						ID:  "_design/player",
+						Rev: "abc",
+					},
+					Rewrites: []interface{}{map[string]interface{}{"from": "/", "method": "GET", "query": map[string]interface{}{"format": "html"}, "to": "_show/html"}},
+				},
+			},
+			additions: 0,
+			changes:   0,
+			deletions: 0,
+		},
 		{
 			desc: "database has too many entries",
 			cache: []DesignDocument{
@@ -1852,3 +2310,1674 @@ func TestSeed(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestSelector(t *testing.T) {
+	sel := And(
+		Eq("type", "player"),
+		Or(Gte("age", 18), Exists("guardian", true)),
+		In("team", "red", "blue"),
+		ElemMatch("scores", Gt("points", 100)),
+		Not(Regex("name", "^test")),
+	)
+	b, err := json.Marshal(sel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"$and":[{"type":{"$eq":"player"}},{"$or":[{"age":{"$gte":18}},{"guardian":{"$exists":true}}]},{"team":{"$in":["red","blue"]}},{"scores":{"$elemMatch":{"points":{"$gt":100}}}},{"$not":{"name":{"$regex":"^test"}}}]}`
+	if string(b) != expected {
+		t.Errorf("expected %s but got %s", expected, b)
+	}
+	b, err = json.Marshal([]Sort{Asc("age"), Desc("name")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `[{"age":"asc"},{"name":"desc"}]` {
+		t.Errorf("unexpected sort %s", b)
+	}
+}
+
+func TestFind(t *testing.T) {
+	skipCouchDB1(t)
+	name, err := RandDBName(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Create(name); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Delete(name)
+	db := client.Use(name)
+	docs := []CouchDoc{
+		&animal{Type: "animal", Animal: "dog", Owner: "zemirco"},
+		&animal{Type: "animal", Animal: "cat", Owner: "zemirco"},
+		&animal{Type: "animal", Animal: "mouse", Owner: "john"},
+	}
+	if _, err := db.Bulk(docs); err != nil {
+		t.Fatal(err)
+	}
+	index := Index{
+		DesignDoc: "animals",
+		Name:      "by-animal",
+		Def: IndexDefinition{
+			Fields: []Sort{Asc("animal")},
+		},
+	}
+	created, err := db.CreateIndex(index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Result != "created" {
+		t.Errorf("expected index to be created but got %s", created.Result)
+	}
+	indexes, err := db.Indexes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(indexes) != 2 {
+		t.Errorf("expected _all_docs and by-animal index but got %v", indexes)
+	}
+	q := FindQuery{
+		Selector:       And(Eq("owner", "zemirco"), Gt("animal", nil)),
+		Sort:           []Sort{Asc("animal")},
+		UseIndex:       []string{"animals", "by-animal"},
+		ExecutionStats: true,
+	}
+	var found []animal
+	res, err := db.Find(q, &found)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 2 || found[0].Animal != "cat" || found[1].Animal != "dog" {
+		t.Errorf("expected cat and dog but got %v", found)
+	}
+	if res.ExecutionStats == nil || res.ExecutionStats.ResultsReturned != 2 {
+		t.Errorf("expected execution stats with 2 results but got %v", res.ExecutionStats)
+	}
+	explain, err := db.Explain(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if explain.Index.Name != "by-animal" {
+		t.Errorf("expected by-animal index to be used but got %s", explain.Index.Name)
+	}
+	if _, err := db.DeleteIndex(Index{DesignDoc: created.ID, Name: created.Name}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCollection(t *testing.T) {
+	name, err := RandDBName(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Create(name); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Delete(name)
+	ctx := context.Background()
+	animals := NewCollection[*animal](client, name)
+	for _, a := range []string{"dog", "cat"} {
+		doc := &animal{Document: Document{ID: a}, Type: "animal", Animal: a}
+		if _, err := animals.Put(ctx, doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	design := DesignDocument{
+		Document: Document{ID: "_design/animals"},
+		Language: langJavaScript,
+		Views: map[string]DesignDocumentView{
+			"byName": {Map: "function(doc) { emit(doc.animal, doc.animal.length) }"},
+		},
+	}
+	if _, err := animals.Database().Put(&design); err != nil {
+		t.Fatal(err)
+	}
+	dog, err := animals.Get(ctx, "dog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dog.Animal != "dog" || dog.Rev == "" {
+		t.Errorf("expected dog with revision but got %v", dog)
+	}
+	list, err := animals.List(ctx, QueryParameters{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].Animal != "cat" || list[1].Animal != "dog" {
+		t.Errorf("expected cat and dog without design document but got %v", list)
+	}
+	docs, err := animals.Query(ctx, "animals", "byName", QueryParameters{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 2 || docs[0].Animal != "cat" {
+		t.Errorf("expected cat and dog from view but got %v", docs)
+	}
+	rows, err := QueryRows[string, int](ctx, animals, "animals", "byName", QueryParameters{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[1].Key != "dog" || rows[1].Value != 3 || rows[1].Doc != nil {
+		t.Errorf("expected typed rows without documents but got %v", rows)
+	}
+	if _, err := animals.Delete(ctx, dog); err != nil {
+		t.Fatal(err)
+	}
+	arbitrary := NewCollection[ArbitraryDoc](client, name)
+	cat, err := arbitrary.Get(ctx, "cat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cat["animal"] != "cat" {
+		t.Errorf("expected cat but got %v", cat)
+	}
+}
+
+func TestRows(t *testing.T) {
+	name, err := RandDBName(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Create(name); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Delete(name)
+	db := client.Use(name)
+	docs := make([]CouchDoc, 25)
+	for i := range docs {
+		// all documents share the same key in the view
+		docs[i] = &animal{Document: Document{ID: fmt.Sprintf("%02d", i)}, Type: "animal", Animal: "dog"}
+	}
+	if _, err := db.Bulk(docs); err != nil {
+		t.Fatal(err)
+	}
+	design := &DesignDocument{
+		Document: Document{ID: "_design/animals"},
+		Language: langJavaScript,
+		Views: map[string]DesignDocumentView{
+			"byAnimal": {Map: "function(doc) { emit(doc.animal, null) }"},
+		},
+	}
+	if _, err := db.Put(design); err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for row, err := range db.View("animals").Rows("byAnimal", QueryParameters{}, 10) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, row.ID)
+	}
+	if len(ids) != 25 || ids[0] != "00" || ids[24] != "24" {
+		t.Errorf("expected 25 rows in order but got %v", ids)
+	}
+	count := 0
+	for _, err := range db.AllDocsRows(QueryParameters{Limit: pointer.Int(12)}, 5) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		count++
+	}
+	if count != 12 {
+		t.Errorf("expected 12 rows but got %d", count)
+	}
+}
+
+type counter struct {
+	Document
+	Count int `json:"count"`
+}
+
+func TestUpdate(t *testing.T) {
+	name, err := RandDBName(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Create(name); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Delete(name)
+	db := client.Use(name)
+	if _, err := db.Put(&counter{Document: Document{ID: "visits"}}); err != nil {
+		t.Fatal(err)
+	}
+	doc := &counter{}
+	calls := 0
+	res, err := db.Update("visits", doc, func(CouchDoc) error {
+		calls++
+		if calls == 1 {
+			// another worker updates the document in the meantime
+			other := &counter{}
+			if err := db.Get(other, "visits"); err != nil {
+				return err
+			}
+			other.Count = 10
+			if _, err := db.Put(other); err != nil {
+				return err
+			}
+		}
+		doc.Count++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Errorf("expected update to be applied twice but got %d", calls)
+	}
+	if !strings.HasPrefix(res.Rev, "3-") {
+		t.Errorf("expected third revision but got %s", res.Rev)
+	}
+	if err := db.Get(doc, "visits"); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Count != 11 {
+		t.Errorf("expected count to be 11 but got %d", doc.Count)
+	}
+	if _, err := db.Update("missing", &counter{}, func(CouchDoc) error { return nil }); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound but got %v", err)
+	}
+	// upsert creates missing documents
+	arbitrary := ArbitraryDoc{}
+	for i := 0; i < 2; i++ {
+		if _, err := db.Upsert("new", arbitrary, func(doc CouchDoc) error {
+			count, _ := arbitrary["count"].(float64)
+			arbitrary["count"] = count + 1
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	result := ArbitraryDoc{}
+	if err := db.Get(result, "new"); err != nil {
+		t.Fatal(err)
+	}
+	if result["count"] != float64(2) {
+		t.Errorf("expected count to be 2 but got %v", result["count"])
+	}
+}
+
+func TestUpdateNotRetried(t *testing.T) {
+	var mu sync.Mutex
+	stored := counter{Document: Document{ID: "visits", Rev: "1-a"}, Count: 1}
+	puts := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodGet:
+			json.NewEncoder(w).Encode(stored)
+		case http.MethodPut:
+			puts++
+			var doc counter
+			json.NewDecoder(r.Body).Decode(&doc)
+			if doc.Rev != stored.Rev {
+				w.WriteHeader(http.StatusConflict)
+				fmt.Fprint(w, `{"error":"conflict","reason":"Document update conflict."}`)
+				return
+			}
+			// the update is committed but the response gets lost
+			stored.Count = doc.Count
+			stored.Rev = fmt.Sprintf("%d-b", puts+1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprint(w, `{"error":"unavailable","reason":"lost"}`)
+		}
+	}))
+	defer ts.Close()
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewClient(u, WithRetry(RetryPolicy{MaxAttempts: 5, MinBackoff: time.Millisecond}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := &counter{}
+	calls := 0
+	_, err = c.Use("db").Update("visits", doc, func(CouchDoc) error {
+		calls++
+		doc.Count++
+		return nil
+	})
+	var e *Error
+	if !errors.As(err, &e) || e.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected the lost response to be reported but got %v", err)
+	}
+	if calls != 1 || puts != 1 || stored.Count != 2 {
+		t.Errorf("expected a single increment but got %d calls, %d requests and count %d", calls, puts, stored.Count)
+	}
+}
+
+func TestBulkResults(t *testing.T) {
+	name, err := RandDBName(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Create(name); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Delete(name)
+	db := client.Use(name)
+	if _, err := db.Put(&DummyDocument{Document: Document{ID: "existing"}}); err != nil {
+		t.Fatal(err)
+	}
+	docs := []CouchDoc{
+		&DummyDocument{Document: Document{ID: "new"}},
+		&DummyDocument{Document: Document{ID: "existing"}},
+	}
+	res, err := db.Bulk(docs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if succeeded := res.Succeeded(); len(succeeded) != 1 || succeeded[0].ID != "new" {
+		t.Errorf("expected new document to succeed but got %v", succeeded)
+	}
+	failed := res.Failed()
+	if len(failed) != 1 || failed[0].ID != "existing" || failed[0].Error != "conflict" {
+		t.Errorf("expected conflict for existing document but got %v", failed)
+	}
+	err = res.Err()
+	var bulkErr *BulkError
+	if !errors.As(err, &bulkErr) || !bulkErr.HasConflict() {
+		t.Errorf("expected bulk error with conflict but got %v", err)
+	}
+	if !errors.Is(err, ErrConflict) {
+		t.Errorf("expected ErrConflict but got %v", err)
+	}
+}
+
+func TestBulkResultsDecode(t *testing.T) {
+	data := `[{"ok":true,"id":"a","rev":"1-a"},{"id":"b","error":"forbidden","reason":"invalid"}]`
+	var res BulkResults
+	if err := json.Unmarshal([]byte(data), &res); err != nil {
+		t.Fatal(err)
+	}
+	if !res[0].Ok || res[0].Rev != "1-a" {
+		t.Errorf("expected first document to succeed but got %v", res[0])
+	}
+	if !res[1].Failed() || res[1].Reason != "invalid" {
+		t.Errorf("expected second document to fail but got %v", res[1])
+	}
+	if errors.Is(res.Err(), ErrConflict) {
+		t.Error("expected forbidden document not to be a conflict")
+	}
+	// a zero value must not panic when printed
+	if msg := (&BulkError{}).Error(); !strings.Contains(msg, "0 documents") {
+		t.Errorf("unexpected message %s", msg)
+	}
+}
+
+func TestBulkWithOptions(t *testing.T) {
+	name, err := RandDBName(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Create(name); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Delete(name)
+	db := client.Use(name)
+	doc := &DummyDocument{
+		Document: Document{ID: "restored", Rev: "3-917fa2381192822767f010b95b45325b"},
+		Foo:      "bar",
+	}
+	res, err := db.BulkWithOptions([]CouchDoc{doc}, BulkOptions{NewEdits: pointer.Bool(false)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := res.Err(); err != nil {
+		t.Fatal(err)
+	}
+	restored := &DummyDocument{}
+	if err := db.Get(restored, "restored"); err != nil {
+		t.Fatal(err)
+	}
+	if restored.Rev != doc.Rev {
+		t.Errorf("expected original revision %s but got %s", doc.Rev, restored.Rev)
+	}
+}
+
+func TestBulkWriter(t *testing.T) {
+	var mu sync.Mutex
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		first := requests == 1
+		mu.Unlock()
+		if first {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprint(w, `{"error":"unavailable","reason":"try again"}`)
+			return
+		}
+		var body struct {
+			Docs []DummyDocument `json:"docs"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		results := make([]map[string]interface{}, len(body.Docs))
+		for i, doc := range body.Docs {
+			results[i] = map[string]interface{}{"id": doc.ID, "rev": "1-abc", "ok": true}
+			if doc.Foo == "conflict" {
+				results[i] = map[string]interface{}{"id": doc.ID, "error": "conflict", "reason": "Document update conflict."}
+			}
+		}
+		json.NewEncoder(w).Encode(results)
+	}))
+	defer ts.Close()
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewClient(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var failed []string
+	var progress int
+	var w *BulkWriter
+	w = NewBulkWriter(context.Background(), c.Use("db"), BulkWriterOptions{
+		BatchSize:   3,
+		Concurrency: 2,
+		Retry:       RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond},
+		OnResult: func(docs []CouchDoc, results BulkResults, err error) {
+			if err != nil {
+				t.Error(err)
+			}
+			for _, r := range results.Failed() {
+				failed = append(failed, r.ID)
+			}
+		},
+		OnProgress: func(stats BulkStats) {
+			progress++
+			// callbacks may read the statistics without deadlocking
+			if current := w.Stats(); current.Requests < stats.Requests {
+				t.Errorf("expected at least %d requests but got %+v", stats.Requests, current)
+			}
+		},
+	})
+	docs := make(chan CouchDoc)
+	go func() {
+		for i := 0; i < 10; i++ {
+			foo := "bar"
+			if i == 7 {
+				foo = "conflict"
+			}
+			docs <- &DummyDocument{Document: Document{ID: fmt.Sprintf("doc%d", i)}, Foo: foo}
+		}
+		close(docs)
+	}()
+	if err := w.WriteFrom(docs); err != nil {
+		t.Fatal(err)
+	}
+	stats, err := w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Written != 9 || stats.Failed != 1 || stats.Requests != 4 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	if len(failed) != 1 || failed[0] != "doc7" {
+		t.Errorf("expected doc7 to fail but got %v", failed)
+	}
+	if progress != 4 {
+		t.Errorf("expected 4 progress reports but got %d", progress)
+	}
+	if requests != 5 {
+		t.Errorf("expected 5 requests including one retry but got %d", requests)
+	}
+	if err := w.Write(&DummyDocument{}); err != ErrBulkWriterClosed {
+		t.Errorf("expected ErrBulkWriterClosed but got %v", err)
+	}
+}
+
+func TestBulkWriterLostResponse(t *testing.T) {
+	var mu sync.Mutex
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the batch is committed but the response gets lost
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, `{"error":"unavailable","reason":"lost"}`)
+	}))
+	defer ts.Close()
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewClient(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		desc     string
+		id       string
+		requests int
+	}{
+		{"documents without id are sent once", "", 1},
+		{"documents with id are retried", "doc", 3},
+	}
+	for _, test := range tests {
+		requests = 0
+		w := NewBulkWriter(context.Background(), c.Use("db"), BulkWriterOptions{
+			Concurrency: 1,
+			Retry:       RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond},
+		})
+		if err := w.Write(&DummyDocument{Document: Document{ID: test.id}}); err != nil {
+			t.Fatal(err)
+		}
+		stats, err := w.Close()
+		var e *Error
+		if !errors.As(err, &e) || e.StatusCode != http.StatusServiceUnavailable {
+			t.Errorf("%s: expected status 503 but got %v", test.desc, err)
+		}
+		if stats.Failed != 1 || requests != test.requests {
+			t.Errorf("%s: expected %d requests but got %d with %+v", test.desc, test.requests, requests, stats)
+		}
+	}
+}
+
+func TestBulkWriterCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// cancel while the first batch is in flight and the second one waits for a worker
+		ioutil.ReadAll(r.Body)
+		cancel()
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer ts.Close()
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewClient(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	var reported []string
+	w := NewBulkWriter(ctx, c.Use("db"), BulkWriterOptions{
+		BatchSize:   1,
+		Concurrency: 1,
+		OnResult: func(docs []CouchDoc, results BulkResults, err error) {
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("expected context.Canceled but got %v", err)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for _, doc := range docs {
+				reported = append(reported, doc.GetID())
+			}
+		},
+	})
+	for i := 0; i < 3; i++ {
+		if err := w.Write(&DummyDocument{Document: Document{ID: fmt.Sprintf("doc%d", i)}}); err != nil {
+			break
+		}
+	}
+	stats, err := w.Close()
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled but got %v", err)
+	}
+	if stats.Written != 0 || stats.Failed != len(reported) || len(reported) < 2 {
+		t.Errorf("expected every written document to be reported as failed but got %+v and %v", stats, reported)
+	}
+}
+
+func TestBulkGet(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/db/_bulk_get" || r.URL.Query().Get("revs") != "true" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		fmt.Fprint(w, `{"results":[
+			{"id":"a","docs":[{"ok":{"_id":"a","_rev":"1-abc","foo":"bar","_revisions":{"start":1,"ids":["abc"]}}}]},
+			{"id":"b","docs":[{"error":{"id":"b","rev":"undefined","error":"not_found","reason":"missing"}}]}
+		]}`)
+	}))
+	defer ts.Close()
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewClient(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := c.Use("db").BulkGet([]BulkGetRef{{ID: "a"}, {ID: "b"}}, BulkGetOptions{Revs: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results but got %d", len(results))
+	}
+	doc := &DummyDocument{}
+	if err := results[0].Decode(doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Foo != "bar" || results[0].Rev != "1-abc" {
+		t.Errorf("unexpected result %+v", results[0])
+	}
+	if err := results[1].Decode(&DummyDocument{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound but got %v", err)
+	}
+}
+
+func TestBulkGetFallback(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/db/_bulk_get":
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			fmt.Fprint(w, `{"error":"method_not_allowed","reason":"Only GET,HEAD,PUT,DELETE,COPY allowed"}`)
+		case "/db/_all_docs":
+			fmt.Fprint(w, `{"total_rows":2,"offset":0,"rows":[
+				{"id":"a","key":"a","value":{"rev":"2-abc"},"doc":{"_id":"a","_rev":"2-abc","foo":"bar"}},
+				{"key":"c","error":"not_found"}
+			]}`)
+		case "/db/b":
+			if r.URL.Query().Get("rev") != "1-def" {
+				t.Errorf("expected revision 1-def but got %s", r.URL.RawQuery)
+			}
+			fmt.Fprint(w, `{"_id":"b","_rev":"1-def","foo":"baz"}`)
+		default:
+			t.Errorf("unexpected request %s", r.URL)
+		}
+	}))
+	defer ts.Close()
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewClient(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := c.Use("db").BulkGet([]BulkGetRef{{ID: "a"}, {ID: "b", Rev: "1-def"}, {ID: "c"}}, BulkGetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results but got %d", len(results))
+	}
+	for i, foo := range []string{"bar", "baz"} {
+		doc := &DummyDocument{}
+		if err := results[i].Decode(doc); err != nil {
+			t.Fatal(err)
+		}
+		if doc.Foo != foo {
+			t.Errorf("expected %s but got %s", foo, doc.Foo)
+		}
+	}
+	if results[2].ID != "c" || !errors.Is(results[2].Error, ErrNotFound) {
+		t.Errorf("expected missing document c but got %+v", results[2])
+	}
+}
+
+func TestBulkGetBadRequest(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error":"bad_request","reason":"Missing JSON list of 'docs'."}`)
+	}))
+	defer ts.Close()
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewClient(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.Use("db").BulkGet([]BulkGetRef{{ID: "a"}, {ID: "b"}}, BulkGetOptions{})
+	var e *Error
+	if !errors.As(err, &e) || e.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected bad request but got %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("expected no fallback requests but got %d requests", requests)
+	}
+}
+
+func TestAttachmentReader(t *testing.T) {
+	content := "hello attachment"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/db/doc/hello.txt" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		switch r.Method {
+		case http.MethodPut:
+			b, _ := ioutil.ReadAll(r.Body)
+			if string(b) != content || r.Header.Get("Content-Type") != "text/plain" {
+				t.Errorf("unexpected upload %q with content type %s", b, r.Header.Get("Content-Type"))
+			}
+			if r.URL.Query().Get("rev") != "1-abc" {
+				t.Errorf("expected revision 1-abc but got %s", r.URL.RawQuery)
+			}
+			fmt.Fprint(w, `{"ok":true,"id":"doc","rev":"2-def"}`)
+		case http.MethodGet:
+			w.Header().Set("Content-Type", "text/plain")
+			w.Header().Set("Content-MD5", "LgLjou6xxxOD5qN2EGFrdQ==")
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			fmt.Fprint(w, content)
+		case http.MethodDelete:
+			if r.URL.Query().Get("rev") != "2-def" {
+				t.Errorf("expected revision 2-def but got %s", r.URL.RawQuery)
+			}
+			fmt.Fprint(w, `{"ok":true,"id":"doc","rev":"3-ghi"}`)
+		}
+	}))
+	defer ts.Close()
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewClient(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := c.Use("db")
+	doc := &DummyDocument{Document: Document{ID: "doc", Rev: "1-abc"}}
+	res, err := db.PutAttachmentReader(doc, "hello.txt", "text/plain", strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Rev != "2-def" {
+		t.Errorf("expected revision 2-def but got %s", res.Rev)
+	}
+	att, err := db.GetAttachment("doc", "hello.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer att.Close()
+	b, err := ioutil.ReadAll(att)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != content {
+		t.Errorf("expected %q but got %q", content, b)
+	}
+	if att.ContentType != "text/plain" || att.Length != int64(len(content)) || att.Digest != "md5-LgLjou6xxxOD5qN2EGFrdQ==" {
+		t.Errorf("unexpected attachment %+v", att)
+	}
+	doc.Rev = res.Rev
+	if _, err := db.DeleteAttachment(doc, "hello.txt"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPutWithAttachments(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength <= 0 {
+			t.Errorf("expected content length but got %d", r.ContentLength)
+		}
+		_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader := multipart.NewReader(r.Body, params["boundary"])
+		part, err := reader.NextPart()
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc := &DummyDocument{}
+		if err := json.NewDecoder(part).Decode(doc); err != nil {
+			t.Fatal(err)
+		}
+		if doc.Foo != "bar" || !doc.Attachments["old.txt"].Stub {
+			t.Errorf("expected document with stub but got %+v", doc)
+		}
+		// parts follow in the order of the attachment names
+		for _, name := range []string{"a.txt", "b.txt"} {
+			if a := doc.Attachments[name]; !a.Follows || a.Length != 9 {
+				t.Errorf("unexpected attachment %s %+v", name, a)
+			}
+			part, err := reader.NextPart()
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, _ := ioutil.ReadAll(part)
+			if string(b) != "content "+name[:1] {
+				t.Errorf("unexpected content %q of %s", b, name)
+			}
+		}
+		fmt.Fprint(w, `{"ok":true,"id":"doc","rev":"2-abc"}`)
+	}))
+	defer ts.Close()
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewClient(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := &DummyDocument{
+		Document: Document{
+			ID:          "doc",
+			Rev:         "1-abc",
+			Attachments: map[string]Attachment{"old.txt": {Stub: true, ContentType: "text/plain"}},
+		},
+		Foo: "bar",
+	}
+	res, err := c.Use("db").PutWithAttachments(doc,
+		AttachmentUpload{Name: "b.txt", ContentType: "text/plain", Body: strings.NewReader("content b")},
+		AttachmentUpload{Name: "a.txt", ContentType: "text/plain", Body: strings.NewReader("content a")},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Rev != "2-abc" {
+		t.Errorf("expected revision 2-abc but got %s", res.Rev)
+	}
+}
+
+func TestGetWithAttachments(t *testing.T) {
+	// the second attachment is sent without name like CouchDB 1.6 does
+	body := "--abc\r\n" +
+		"Content-Type: application/json\r\n\r\n" +
+		`{"_id":"doc","_rev":"2-abc","foo":"bar","_attachments":{` +
+		`"dir/a.txt":{"content_type":"text/plain","follows":true,"length":9,"encoding":"gzip","digest":"md5-AAAAAAAAAAAAAAAAAAAAAA=="},` +
+		`"b.txt":{"content_type":"image/png","follows":true,"length":9,"digest":"md5-zU5qwNTiTHAqtGmltep7LA=="}}}` + "\r\n" +
+		"--abc\r\n" +
+		"Content-Disposition: attachment; filename=\"dir/a.txt\"\r\n\r\n" +
+		"content a\r\n" +
+		"--abc\r\n\r\n" +
+		"content b\r\n" +
+		"--abc--"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the digest of compressed attachments is not verified against the decompressed parts
+		if r.URL.Query().Get("attachments") != "true" || r.URL.Query().Get("att_encoding_info") != "true" {
+			t.Errorf("expected attachments and att_encoding_info but got %s", r.URL.RawQuery)
+		}
+		w.Header().Set("Content-Type", `multipart/related; boundary="abc"`)
+		fmt.Fprint(w, body)
+	}))
+	defer ts.Close()
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewClient(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := c.Use("db")
+	doc := &DummyDocument{}
+	if err := db.GetWithAttachments(doc, "doc"); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Foo != "bar" {
+		t.Errorf("expected foo bar but got %s", doc.Foo)
+	}
+	for name, content := range map[string]string{"dir/a.txt": "content a", "b.txt": "content b"} {
+		a := doc.Attachments[name]
+		if string(a.Content) != content || !a.Stub || a.Follows {
+			t.Errorf("unexpected attachment %s %+v", name, a)
+		}
+	}
+	arbitrary := ArbitraryDoc{}
+	if err := db.GetWithAttachments(arbitrary, "doc"); err != nil {
+		t.Fatal(err)
+	}
+	attachments, ok := arbitrary["_attachments"].(map[string]Attachment)
+	if !ok || string(attachments["b.txt"].Content) != "content b" {
+		t.Errorf("unexpected attachments %+v", arbitrary["_attachments"])
+	}
+}
+
+func TestAttachmentDigest(t *testing.T) {
+	content := "hello attachment"
+	digest, err := Digest(strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if digest != "md5-LgLjou6xxxOD5qN2EGFrdQ==" {
+		t.Errorf("unexpected digest %s", digest)
+	}
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	zw.Write([]byte(content))
+	zw.Close()
+	// CouchDB computes the digest of compressed attachments over the stored gzip bytes
+	sum := md5.Sum(compressed.Bytes())
+	compressedDigest := base64.StdEncoding.EncodeToString(sum[:])
+	var uploads int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/db/doc":
+			if r.URL.Query().Get("att_encoding_info") != "true" {
+				t.Errorf("expected att_encoding_info but got %s", r.URL.RawQuery)
+			}
+			fmt.Fprintf(w, `{"_id":"doc","_rev":"1-abc","_attachments":{
+				"hello.bin":{"content_type":"application/octet-stream","digest":"md5-LgLjou6xxxOD5qN2EGFrdQ==","stub":true},
+				"hello.txt":{"content_type":"text/plain","digest":"md5-%s","encoding":"gzip","stub":true}
+			}}`, compressedDigest)
+		case r.Method == http.MethodPut:
+			uploads++
+			if r.Header.Get("Content-MD5") == "" || r.ContentLength <= 0 {
+				t.Errorf("expected Content-MD5 and Content-Length but got %v", r.Header)
+			}
+			fmt.Fprint(w, `{"ok":true,"id":"doc","rev":"2-abc"}`)
+		case r.URL.Path == "/db/doc/corrupt.txt":
+			w.Header().Set("ETag", `"LgLjou6xxxOD5qN2EGFrdQ=="`)
+			fmt.Fprint(w, "corrupted attachment")
+		case r.URL.Path == "/db/doc/corrupt.gz":
+			w.Header().Set("ETag", `"LgLjou6xxxOD5qN2EGFrdQ=="`)
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(compressed.Bytes())
+		default:
+			if r.Header.Get("Accept-Encoding") != "gzip" {
+				t.Errorf("expected gzip to be accepted")
+			}
+			w.Header().Set("ETag", `"`+compressedDigest+`"`)
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(compressed.Bytes())
+		}
+	}))
+	defer ts.Close()
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewClient(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := c.Use("db")
+	att, err := db.GetAttachment("doc", "hello.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(att)
+	att.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != content || att.Encoding != "gzip" || att.Digest != "md5-"+compressedDigest {
+		t.Errorf("unexpected attachment %q %+v", b, att)
+	}
+	for _, name := range []string{"corrupt.txt", "corrupt.gz"} {
+		att, err = db.GetAttachment("doc", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ioutil.ReadAll(att); !errors.Is(err, ErrDigestMismatch) {
+			t.Errorf("expected ErrDigestMismatch for %s but got %v", name, err)
+		}
+		att.Close()
+	}
+	doc := &DummyDocument{Document: Document{ID: "doc", Rev: "1-abc"}}
+	if _, changed, err := db.PutAttachmentIfChanged(doc, "hello.bin", "application/octet-stream", strings.NewReader(content)); err != nil || changed {
+		t.Errorf("expected unchanged attachment to be skipped but got %v %v", changed, err)
+	}
+	if _, changed, err := db.PutAttachmentIfChanged(doc, "hello.bin", "application/octet-stream", strings.NewReader("new content")); err != nil || !changed {
+		t.Errorf("expected changed attachment to be uploaded but got %v %v", changed, err)
+	}
+	// the digest of compressed attachments cannot be compared
+	if _, changed, err := db.PutAttachmentIfChanged(doc, "hello.txt", "text/plain", strings.NewReader(content)); err != nil || !changed {
+		t.Errorf("expected compressed attachment to be uploaded but got %v %v", changed, err)
+	}
+	if uploads != 2 {
+		t.Errorf("expected 2 uploads but got %d", uploads)
+	}
+}
+
+func TestTextAttachmentRoundTrip(t *testing.T) {
+	name, err := RandDBName(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Create(name); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Delete(name)
+	db := client.Use(name)
+	// CouchDB stores text attachments compressed with a digest of the gzip bytes
+	content := strings.Repeat("hello compressible attachment\n", 100)
+	doc := &DummyDocument{Document: Document{ID: "doc"}}
+	res, err := db.PutAttachmentReader(doc, "hello.txt", "text/plain", strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	att, err := db.GetAttachment("doc", "hello.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(att)
+	att.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != content {
+		t.Errorf("unexpected content of length %d", len(b))
+	}
+	loaded := &DummyDocument{}
+	if err := db.GetWithAttachments(loaded, "doc"); err != nil {
+		t.Fatal(err)
+	}
+	if string(loaded.Attachments["hello.txt"].Content) != content {
+		t.Errorf("unexpected attachment %+v", loaded.Attachments["hello.txt"])
+	}
+	doc.Rev = res.Rev
+	if _, _, err := db.PutAttachmentIfChanged(doc, "hello.txt", "text/plain", strings.NewReader(content)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetWithOptions(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("open_revs") == "" {
+			if q.Get("revs") != "true" || q.Get("conflicts") != "true" || q.Get("local_seq") != "true" {
+				t.Errorf("unexpected query %s", r.URL.RawQuery)
+			}
+			fmt.Fprint(w, `{"_id":"doc","_rev":"3-c","foo":"bar","_local_seq":7,
+				"_revisions":{"start":3,"ids":["c","b","a"]},"_conflicts":["3-d"]}`)
+			return
+		}
+		if q.Get("open_revs") != "all" {
+			t.Errorf("expected all open revisions but got %s", r.URL.RawQuery)
+		}
+		w.Header().Set("Content-Type", `multipart/mixed; boundary="abc"`)
+		fmt.Fprint(w, "--abc\r\n"+
+			"Content-Type: application/json\r\n\r\n"+
+			`{"_id":"doc","_rev":"3-c","foo":"bar"}`+"\r\n"+
+			"--abc\r\n"+
+			"Content-Type: multipart/related; boundary=\"def\"\r\n\r\n"+
+			"--def\r\n"+
+			"Content-Type: application/json\r\n\r\n"+
+			`{"_id":"doc","_rev":"3-d","foo":"baz","_attachments":{"a.txt":{"follows":true,"length":1}}}`+"\r\n"+
+			"--def\r\n\r\n"+
+			"a\r\n"+
+			"--def--\r\n"+
+			"--abc\r\n"+
+			"Content-Type: application/json\r\n\r\n"+
+			`{"missing":"1-x"}`+"\r\n"+
+			"--abc--")
+	}))
+	defer ts.Close()
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewClient(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := c.Use("db")
+	doc := &DummyDocument{}
+	meta, err := db.GetWithOptions(doc, "doc", GetOptions{Revs: true, Conflicts: true, LocalSeq: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Foo != "bar" || meta.Rev != "3-c" || meta.LocalSeq != "7" {
+		t.Errorf("unexpected document %+v with meta %+v", doc, meta)
+	}
+	if revs := meta.Revisions.Revs(); !reflect.DeepEqual(revs, []string{"3-c", "2-b", "1-a"}) {
+		t.Errorf("unexpected revisions %v", revs)
+	}
+	if !reflect.DeepEqual(meta.Conflicts, []string{"3-d"}) {
+		t.Errorf("unexpected conflicts %v", meta.Conflicts)
+	}
+	revs, err := db.GetOpenRevs("doc", nil, GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revs) != 3 {
+		t.Fatalf("expected 3 revisions but got %d", len(revs))
+	}
+	for i, foo := range []string{"bar", "baz"} {
+		doc := &DummyDocument{}
+		if err := revs[i].Decode(doc); err != nil {
+			t.Fatal(err)
+		}
+		if doc.Foo != foo {
+			t.Errorf("expected %s but got %s", foo, doc.Foo)
+		}
+	}
+	if revs[2].Rev != "1-x" || !errors.Is(revs[2].Decode(&DummyDocument{}), ErrNotFound) {
+		t.Errorf("expected missing revision but got %+v", revs[2])
+	}
+}
+
+func TestResolvers(t *testing.T) {
+	leaves := []ArbitraryDoc{
+		{"_id": "doc", "_rev": "2-a", "updated": "2020-01-01T00:00:00Z", "name": "a", "address": map[string]interface{}{"city": "Berlin"}},
+		{"_id": "doc", "_rev": "2-b", "updated": "2021-01-01T00:00:00Z", "name": "b", "address": map[string]interface{}{"zip": "10115"}, "age": float64(3)},
+	}
+	doc, err := LastWriteWins("updated")(leaves)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc["name"] != "b" {
+		t.Errorf("expected latest leaf but got %v", doc)
+	}
+	// mixed types give the same winner regardless of the order of the leaves
+	mixed := []ArbitraryDoc{
+		{"name": "missing"},
+		{"name": "number", "updated": float64(1600000000)},
+		{"name": "string", "updated": "2020-01-01T00:00:00Z"},
+		{"name": "bool", "updated": true},
+	}
+	for i := range mixed {
+		rotated := append(append([]ArbitraryDoc{}, mixed[i:]...), mixed[:i]...)
+		doc, err := LastWriteWins("updated")(rotated)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if doc["name"] != "string" {
+			t.Errorf("expected string to win but got %v", doc)
+		}
+	}
+	doc, err = DeepMerge(leaves)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{"city": "Berlin", "zip": "10115"}
+	if doc["name"] != "a" || doc["age"] != float64(3) || !reflect.DeepEqual(doc["address"], expected) {
+		t.Errorf("unexpected merged document %v", doc)
+	}
+	if _, ok := leaves[0]["address"].(map[string]interface{})["zip"]; ok {
+		t.Error("expected leaves to stay unchanged")
+	}
+	doc, err = MergeWith(func(leaves []*DummyDocument) (*DummyDocument, error) {
+		return &DummyDocument{Foo: leaves[0].Document.Rev + leaves[1].Document.Rev}, nil
+	})(leaves)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc["foo"] != "2-a2-b" {
+		t.Errorf("unexpected custom merge %v", doc)
+	}
+}
+
+func TestResolveConflict(t *testing.T) {
+	var bulk struct {
+		Docs []ArbitraryDoc `json:"docs"`
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/db/_changes":
+			// pages can be shorter than the limit before the end of the feed
+			switch r.URL.Query().Get("since") {
+			case "":
+				fmt.Fprint(w, `{"results":[
+					{"seq":1,"id":"other","changes":[{"rev":"1-c"}],"doc":{"_id":"other","_rev":"1-c"}}
+				],"last_seq":1,"pending":1}`)
+			case "1":
+				fmt.Fprint(w, `{"results":[
+					{"seq":2,"id":"doc","changes":[{"rev":"2-a"}],"doc":{"_id":"doc","_rev":"2-a","_conflicts":["2-b"]}}
+				],"last_seq":2,"pending":0}`)
+			default:
+				fmt.Fprint(w, `{"results":[],"last_seq":2,"pending":0}`)
+			}
+		case r.URL.Path == "/db/_bulk_docs":
+			json.NewDecoder(r.Body).Decode(&bulk)
+			fmt.Fprint(w, `[{"ok":true,"id":"doc","rev":"3-x"},{"ok":true,"id":"doc","rev":"3-y"}]`)
+		case r.URL.Query().Get("open_revs") != "":
+			fmt.Fprint(w, `[{"ok":{"_id":"doc","_rev":"2-b","n":2,"t":2}},{"ok":{"_id":"doc","_rev":"2-a","n":1,"t":1}}]`)
+		default:
+			fmt.Fprint(w, `{"_id":"doc","_rev":"2-a","n":1,"t":1,"_conflicts":["2-b"]}`)
+		}
+	}))
+	defer ts.Close()
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewClient(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := c.Use("db")
+	ids, err := db.Conflicts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ids, []string{"doc"}) {
+		t.Errorf("expected conflicted doc but got %v", ids)
+	}
+	conflict, err := db.LoadConflict("doc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conflict.Leaves) != 2 || conflict.Leaves[0].GetRev() != "2-a" {
+		t.Fatalf("expected winner first but got %v", conflict.Leaves)
+	}
+	if _, err := db.ResolveConflict("doc", LastWriteWins("t")); err != nil {
+		t.Fatal(err)
+	}
+	if len(bulk.Docs) != 2 {
+		t.Fatalf("expected 2 documents but got %v", bulk.Docs)
+	}
+	if merged := bulk.Docs[0]; merged["_rev"] != "2-a" || merged["n"] != float64(2) || merged["_conflicts"] != nil {
+		t.Errorf("unexpected merged document %v", merged)
+	}
+	if loser := bulk.Docs[1]; loser["_rev"] != "2-b" || loser["_deleted"] != true {
+		t.Errorf("expected deleted loser but got %v", loser)
+	}
+}
+
+func TestCopy(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "COPY" || r.URL.Path != "/db/template" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+		if r.URL.Query().Get("rev") != "1-abc" {
+			t.Errorf("expected source revision 1-abc but got %s", r.URL.RawQuery)
+		}
+		if d := r.Header.Get("Destination"); d != "copy?rev=2-def" {
+			t.Errorf("unexpected destination %s", d)
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"ok":true,"id":"copy","rev":"3-ghi"}`)
+	}))
+	defer ts.Close()
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewClient(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := c.Use("db").Copy("template", "copy", CopyOptions{Rev: "1-abc", DestinationRev: "2-def"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.ID != "copy" || res.Rev != "3-ghi" {
+		t.Errorf("unexpected response %+v", res)
+	}
+}
+
+func TestQueryParametersKeys(t *testing.T) {
+	params := QueryParameters{
+		Key:      "john",
+		Keys:     [][]interface{}{{"user", 42}},
+		StartKey: []interface{}{"user", 42},
+		EndKey:   []interface{}{"user", 42, map[string]interface{}{}},
+		Update:   pointer.String("lazy"),
+	}
+	q, err := params.values()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"key":      `"john"`,
+		"keys":     `[["user",42]]`,
+		"startkey": `["user",42]`,
+		"endkey":   `["user",42,{}]`,
+		"update":   "lazy",
+	}
+	for name, value := range expected {
+		if q.Get(name) != value {
+			t.Errorf("expected %s to be %s but got %s", name, value, q.Get(name))
+		}
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		if string(b) != `{"keys":[["user",42]]}`+"\n" {
+			t.Errorf("unexpected body %s", b)
+		}
+		if r.URL.Query().Get("keys") != "" {
+			t.Errorf("expected keys only in body but got %s", r.URL.RawQuery)
+		}
+		fmt.Fprint(w, `{"rows":[{"id":"john","key":["user",42],"value":null}]}`)
+	}))
+	defer ts.Close()
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewClient(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := c.Use("db").View("users").Post("byID", nil, QueryParameters{Keys: [][]interface{}{{"user", 42}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Rows) != 1 {
+		t.Errorf("expected 1 row but got %d", len(res.Rows))
+	}
+	// typed nils are unset and not encoded as null
+	q, err = (&QueryParameters{Keys: []string(nil), StartKey: (*int)(nil)}).values()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Encode() != "" {
+		t.Errorf("expected no parameters but got %s", q.Encode())
+	}
+	// keys encoded by the caller would be encoded twice
+	if _, err := (&QueryParameters{Key: pointer.String(`"foo"`)}).values(); err == nil {
+		t.Error("expected error for *string key")
+	}
+}
+
+func TestRawView(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/db/_design/stats/_view/count":
+			if r.URL.Query().Get("group") != "true" {
+				t.Errorf("expected group but got %s", r.URL.RawQuery)
+			}
+			fmt.Fprint(w, `{"rows":[{"key":["john",2],"value":3},{"key":["mary",1],"value":4}]}`)
+		case "/db/_all_docs":
+			if r.Method != http.MethodGet || r.URL.Query().Get("keys") != `["john","gone","missing"]` {
+				t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			}
+			fmt.Fprint(w, `{"total_rows":2,"offset":0,"rows":[
+				{"id":"john","key":"john","value":{"rev":"1-a"},"doc":{"_id":"john","_rev":"1-a","foo":"bar"}},
+				{"id":"gone","key":"gone","value":{"rev":"2-b","deleted":true},"doc":null},
+				{"key":"missing","error":"not_found"}
+			]}`)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer ts.Close()
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewClient(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := c.Use("db")
+	res, err := db.View("stats").GetRaw("count", QueryParameters{Group: pointer.Bool(true)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	var counts []int
+	for _, row := range res.Rows {
+		var key []json.RawMessage
+		if err := row.DecodeKey(&key); err != nil {
+			t.Fatal(err)
+		}
+		var name string
+		if err := json.Unmarshal(key[0], &name); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+		var count int
+		if err := row.DecodeValue(&count); err != nil {
+			t.Fatal(err)
+		}
+		counts = append(counts, count)
+	}
+	if !reflect.DeepEqual(names, []string{"john", "mary"}) || !reflect.DeepEqual(counts, []int{3, 4}) {
+		t.Errorf("unexpected rows %v %v", names, counts)
+	}
+	all, err := db.AllDocsRaw(&QueryParameters{Keys: []string{"john", "gone", "missing"}, IncludeDocs: pointer.Bool(true)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if all.TotalRows != 2 || len(all.Rows) != 3 {
+		t.Fatalf("unexpected response %+v", all)
+	}
+	doc := &DummyDocument{}
+	if err := all.Rows[0].DecodeDoc(doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.ID != "john" || doc.Rev != "1-a" || doc.Foo != "bar" {
+		t.Errorf("unexpected document %+v", doc)
+	}
+	if err := all.Rows[1].DecodeDoc(&DummyDocument{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected not found for deleted document but got %v", err)
+	}
+	row := all.Rows[2]
+	if row.Error == nil || row.Error.Type != "not_found" || row.Error.Method != http.MethodGet || !strings.Contains(row.Error.URL, "_all_docs") {
+		t.Fatalf("unexpected error %+v", row.Error)
+	}
+	if err := row.DecodeDoc(ArbitraryDoc{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected not found but got %v", err)
+	}
+	var key string
+	if err := row.DecodeKey(&key); err != nil || key != "missing" {
+		t.Errorf("unexpected key %q %v", key, err)
+	}
+	// the untyped rows surface the error as well
+	var plain ViewResponse
+	if err := json.Unmarshal([]byte(`{"rows":[{"key":"missing","error":"not_found"}]}`), &plain); err != nil {
+		t.Fatal(err)
+	}
+	if plain.Rows[0].Error != "not_found" {
+		t.Errorf("unexpected row %+v", plain.Rows[0])
+	}
+}
+
+func TestQueries(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/db/_design/users/_view/byName/queries" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+		var body struct {
+			Queries []map[string]interface{} `json:"queries"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		expected := []map[string]interface{}{
+			{"key": "john"},
+			{"start_key": []interface{}{"a"}, "limit": float64(2)},
+		}
+		if !reflect.DeepEqual(body.Queries, expected) {
+			t.Errorf("unexpected queries %v", body.Queries)
+		}
+		fmt.Fprint(w, `{"results":[
+			{"total_rows":3,"offset":0,"rows":[{"id":"1","key":"john","value":null}]},
+			{"total_rows":3,"offset":1,"rows":[{"id":"2","key":"mary","value":null},{"id":"3","key":"zoe","value":null}]}
+		]}`)
+	}))
+	defer ts.Close()
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewClient(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := c.Use("db").View("users").Queries("byName", []QueryParameters{
+		{Key: "john"},
+		{StartKey: []string{"a"}, Limit: pointer.Int(2)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 2 || len(res[0].Rows) != 1 || len(res[1].Rows) != 2 || res[1].Offset != 1 || res[1].Rows[1].ID != "3" {
+		t.Errorf("unexpected results %+v", res)
+	}
+}
+
+func TestQueriesFallback(t *testing.T) {
+	var mu sync.Mutex
+	requests := map[string]int{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.Method+" "+r.URL.Path]++
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/db/_all_docs/queries":
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error":"not_found","reason":"missing"}`)
+		case "/db/_all_docs":
+			key := r.URL.Query().Get("key")
+			fmt.Fprintf(w, `{"total_rows":2,"offset":0,"rows":[{"id":%s,"key":%s,"value":{"rev":"1-a"}}]}`, key, key)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer ts.Close()
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewClient(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	queries := []QueryParameters{{Key: "a"}, {Key: "b"}, {Key: "c"}}
+	res, err := c.Use("db").AllDocsQueries(queries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, id := range []string{"a", "b", "c"} {
+		if len(res[i].Rows) != 1 || res[i].Rows[0].ID != id {
+			t.Errorf("unexpected result %d: %+v", i, res[i])
+		}
+	}
+	if requests["POST /db/_all_docs/queries"] != 1 || requests["GET /db/_all_docs"] != 3 {
+		t.Errorf("unexpected requests %v", requests)
+	}
+}
+
+func TestQueriesBadRequest(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error":"query_parse_error","reason":"Invalid value for integer: \"x\""}`)
+	}))
+	defer ts.Close()
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewClient(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.Use("db").View("users").Queries("byName", []QueryParameters{{Key: "a"}, {Key: "b"}})
+	var e *Error
+	if !errors.As(err, &e) || e.Type != "query_parse_error" {
+		t.Errorf("expected query_parse_error but got %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("expected no fallback requests but got %d requests", requests)
+	}
+}
+
+func TestHandlers(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /db/_design/app/_show/html/john":
+			if r.URL.Query().Get("lang") != "en" {
+				t.Errorf("unexpected query %s", r.URL.RawQuery)
+			}
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, "<h1>john</h1>")
+		case "GET /db/_design/app/_list/csv/other/byName":
+			q := r.URL.Query()
+			if q.Get("startkey") != `"a"` || q.Get("sep") != ";" {
+				t.Errorf("unexpected query %s", r.URL.RawQuery)
+			}
+			w.Header().Set("Content-Type", "text/csv")
+			fmt.Fprint(w, "id;name\n1;john\n")
+		case "PUT /db/_design/app/_update/increment/counter":
+			if r.URL.Query().Get("by") != "2" {
+				t.Errorf("unexpected query %s", r.URL.RawQuery)
+			}
+			w.Header().Set("Content-Type", "text/plain")
+			w.Header().Set("X-Couch-Update-NewRev", "3-abc")
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, "4")
+		case "POST /db/_design/app/_update/create":
+			body, _ := ioutil.ReadAll(r.Body)
+			if r.Header.Get("Content-Type") != "application/json" || string(body) != `{"name":"mary"}` {
+				t.Errorf("unexpected body %s", body)
+			}
+			w.Header().Set("X-Couch-Update-NewRev", "1-def")
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, "created")
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer ts.Close()
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewClient(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := c.Use("db").View("app")
+	read := func(res *HandlerResponse) string {
+		defer res.Body.Close()
+		b, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+	show, err := app.Show("html", "john", url.Values{"lang": {"en"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if show.ContentType != "text/html; charset=utf-8" || read(show) != "<h1>john</h1>" {
+		t.Errorf("unexpected show response %+v", show)
+	}
+	list, err := app.List("csv", "other/byName", QueryParameters{StartKey: "a"}, url.Values{"sep": {";"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.ContentType != "text/csv" || read(list) != "id;name\n1;john\n" {
+		t.Errorf("unexpected list response %+v", list)
+	}
+	update, err := app.Update("increment", "counter", url.Values{"by": {"2"}}, nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if update.StatusCode != http.StatusCreated || update.NewRev != "3-abc" || read(update) != "4" {
+		t.Errorf("unexpected update response %+v", update)
+	}
+	create, err := app.Update("create", "", nil, strings.NewReader(`{"name":"mary"}`), "application/json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if create.NewRev != "1-def" || read(create) != "created" {
+		t.Errorf("unexpected update response %+v", create)
+	}
+}
+
+func TestDesignDocumentHandlers(t *testing.T) {
+	design := DesignDocument{
+		Document: Document{ID: "_design/app"},
+		Updates:  map[string]string{"increment": "function(doc, req) { return [doc, 'ok'] }"},
+		Rewrites: []DesignDocumentRewrite{{From: "/users/:id", To: "_show/html/:id", Method: "GET"}},
+	}
+	b, err := json.Marshal(design)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded DesignDocument
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Updates["increment"] == "" {
+		t.Errorf("missing update function in %s", b)
+	}
+	rewrites, ok := decoded.Rewrites.([]interface{})
+	if !ok || len(rewrites) != 1 {
+		t.Errorf("unexpected rewrites %v", decoded.Rewrites)
+	}
+	// rewrite functions are stored as string
+	if err := json.Unmarshal([]byte(`{"_id":"_design/app","rewrites":"function(req) { return '/' }"}`), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := decoded.Rewrites.(string); !ok {
+		t.Errorf("unexpected rewrites %v", decoded.Rewrites)
+	}
+}
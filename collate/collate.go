@@ -0,0 +1,310 @@
+// Package collate implements the order CouchDB uses to sort view keys.
+//
+// Values of different types are sorted as
+//
+//	null < false < true < numbers < strings < arrays < objects
+//
+// Strings are compared like the ICU collation of CouchDB: letters are compared
+// case-insensitively first, so "a" < "A" < "aa" < "b", and punctuation sorts before digits
+// and letters. The order is exact for ASCII. Other characters are compared
+// case-insensitively by code point, which differs from ICU for accented letters and scripts
+// other than Latin.
+//
+// http://docs.couchdb.org/en/stable/ddocs/views/collation.html
+package collate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/zemirco/couchdb"
+)
+
+// Compare returns -1 if a sorts before b, 1 if a sorts after b and 0 if both are equal.
+// a and b can be any values that can be encoded to JSON.
+// Objects are compared member by member in the order they are encoded,
+// maps are therefore compared with sorted keys.
+// Compare panics if a value cannot be encoded to JSON.
+func Compare(a, b interface{}) int {
+	va, err := parse(a)
+	if err != nil {
+		panic(err)
+	}
+	vb, err := parse(b)
+	if err != nil {
+		panic(err)
+	}
+	return compare(va, vb)
+}
+
+// CompareJSON is like Compare for encoded values and keeps the order of object members.
+func CompareJSON(a, b []byte) (int, error) {
+	va, err := decode(a)
+	if err != nil {
+		return 0, err
+	}
+	vb, err := decode(b)
+	if err != nil {
+		return 0, err
+	}
+	return compare(va, vb), nil
+}
+
+// PrefixRange returns the start and end key of all compound keys beginning with prefix.
+//
+//	start, end := collate.PrefixRange("org1")
+//	params := couchdb.QueryParameters{StartKey: start, EndKey: end}
+//
+// matches ["org1"], ["org1", 2] and ["org1", "a", "b"] but not ["org2"].
+// Keys with a non-empty object right after the prefix are not matched.
+// Swap both keys for descending queries.
+func PrefixRange(prefix ...interface{}) (startKey, endKey []interface{}) {
+	startKey = append([]interface{}{}, prefix...)
+	// an empty object sorts after all values but other objects
+	endKey = append(append([]interface{}{}, prefix...), map[string]interface{}{})
+	return startKey, endKey
+}
+
+// StringPrefixRange returns the start and end key of all string keys beginning with prefix.
+func StringPrefixRange(prefix string) (startKey, endKey string) {
+	// a high code point sorts after all characters that are usually part of keys
+	return prefix, prefix + "\ufff0"
+}
+
+// SortRows sorts rows in the order of a view response: by key and then by document id.
+// Document ids are compared byte by byte like CouchDB does for equal keys.
+// Rows of _all_docs are sorted by id byte by byte, use SortRowsByID for them.
+func SortRows(rows []couchdb.Row) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		return lessRow(rows[i], rows[j])
+	})
+}
+
+// SortRowsByID sorts rows byte by byte by id like _all_docs does.
+func SortRowsByID(rows []couchdb.Row) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].ID < rows[j].ID
+	})
+}
+
+// MergeRows merges rows of several views or databases which are sorted in ascending order
+// into one list in the same order, e.g. to combine the responses of sharded databases.
+func MergeRows(lists ...[]couchdb.Row) []couchdb.Row {
+	var merged []couchdb.Row
+	for _, list := range lists {
+		merged = mergeTwo(merged, list)
+	}
+	return merged
+}
+
+func mergeTwo(a, b []couchdb.Row) []couchdb.Row {
+	merged := make([]couchdb.Row, 0, len(a)+len(b))
+	for len(a) > 0 && len(b) > 0 {
+		// take from a on ties to keep the order of the lists
+		if lessRow(b[0], a[0]) {
+			merged = append(merged, b[0])
+			b = b[1:]
+			continue
+		}
+		merged = append(merged, a[0])
+		a = a[1:]
+	}
+	merged = append(merged, a...)
+	return append(merged, b...)
+}
+
+func lessRow(a, b couchdb.Row) bool {
+	if c := Compare(a.Key, b.Key); c != 0 {
+		return c < 0
+	}
+	return a.ID < b.ID
+}
+
+// kind is the type of a JSON value in collation order.
+type kind int
+
+const (
+	kindNull kind = iota
+	kindFalse
+	kindTrue
+	kindNumber
+	kindString
+	kindArray
+	kindObject
+)
+
+// value is a decoded JSON value that keeps the order of object members.
+type value struct {
+	kind    kind
+	number  float64
+	str     string
+	items   []value
+	members []member
+}
+
+type member struct {
+	key   string
+	value value
+}
+
+func parse(v interface{}) (value, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return value{}, err
+	}
+	return decode(b)
+}
+
+func decode(data []byte) (value, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return decodeValue(dec)
+}
+
+func decodeValue(dec *json.Decoder) (value, error) {
+	t, err := dec.Token()
+	if err != nil {
+		return value{}, err
+	}
+	switch t := t.(type) {
+	case nil:
+		return value{kind: kindNull}, nil
+	case bool:
+		if t {
+			return value{kind: kindTrue}, nil
+		}
+		return value{kind: kindFalse}, nil
+	case json.Number:
+		n, err := t.Float64()
+		if err != nil {
+			return value{}, err
+		}
+		return value{kind: kindNumber, number: n}, nil
+	case string:
+		return value{kind: kindString, str: t}, nil
+	case json.Delim:
+		switch t {
+		case '[':
+			v := value{kind: kindArray}
+			for dec.More() {
+				item, err := decodeValue(dec)
+				if err != nil {
+					return value{}, err
+				}
+				v.items = append(v.items, item)
+			}
+			_, err := dec.Token()
+			return v, err
+		case '{':
+			v := value{kind: kindObject}
+			for dec.More() {
+				key, err := dec.Token()
+				if err != nil {
+					return value{}, err
+				}
+				item, err := decodeValue(dec)
+				if err != nil {
+					return value{}, err
+				}
+				v.members = append(v.members, member{key: key.(string), value: item})
+			}
+			_, err := dec.Token()
+			return v, err
+		}
+	}
+	return value{}, fmt.Errorf("collate: unexpected token %v", t)
+}
+
+func compare(a, b value) int {
+	if a.kind != b.kind {
+		return sign(int(a.kind) - int(b.kind))
+	}
+	switch a.kind {
+	case kindNumber:
+		switch {
+		case a.number < b.number:
+			return -1
+		case a.number > b.number:
+			return 1
+		}
+		return 0
+	case kindString:
+		return compareStrings(a.str, b.str)
+	case kindArray:
+		for i := 0; i < len(a.items) && i < len(b.items); i++ {
+			if c := compare(a.items[i], b.items[i]); c != 0 {
+				return c
+			}
+		}
+		return sign(len(a.items) - len(b.items))
+	case kindObject:
+		for i := 0; i < len(a.members) && i < len(b.members); i++ {
+			if c := compareStrings(a.members[i].key, b.members[i].key); c != 0 {
+				return c
+			}
+			if c := compare(a.members[i].value, b.members[i].value); c != 0 {
+				return c
+			}
+		}
+		return sign(len(a.members) - len(b.members))
+	}
+	return 0
+}
+
+// punctuation lists the ASCII characters other than digits and letters in ICU order.
+const punctuation = "\t\n\v\f\r `^_-,;:!?.'\"()[]{}@*/\\&#%+<=>|~$"
+
+// weight returns the primary weight of a character.
+// Upper and lower case letters have the same weight.
+func weight(r rune) int {
+	switch {
+	case r < 128 && strings.ContainsRune(punctuation, r):
+		return strings.IndexRune(punctuation, r) + 1
+	case r >= '0' && r <= '9':
+		return 100 + int(r-'0')
+	case r < 128 && unicode.IsLetter(r):
+		return 200 + int(unicode.ToLower(r)-'a')
+	case r < 128:
+		// remaining control characters
+		return 0
+	}
+	return 1000 + int(unicode.ToLower(r))
+}
+
+// compareStrings compares the primary weights of both strings first
+// and decides ties with lower case before upper case.
+func compareStrings(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	for i := 0; i < len(ra) && i < len(rb); i++ {
+		if c := sign(weight(ra[i]) - weight(rb[i])); c != 0 {
+			return c
+		}
+	}
+	if c := sign(len(ra) - len(rb)); c != 0 {
+		return c
+	}
+	for i := range ra {
+		upperA, upperB := unicode.IsUpper(ra[i]), unicode.IsUpper(rb[i])
+		if upperA != upperB {
+			if upperA {
+				return 1
+			}
+			return -1
+		}
+	}
+	return strings.Compare(a, b)
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,150 @@
+package collate
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/zemirco/couchdb"
+)
+
+// sorted is the example of the CouchDB documentation in ascending order.
+// http://docs.couchdb.org/en/stable/ddocs/views/collation.html#collation-specification
+var sorted = []interface{}{
+	nil,
+	false,
+	true,
+	1,
+	2,
+	3.0,
+	4,
+	"a",
+	"A",
+	"aa",
+	"b",
+	"B",
+	"ba",
+	"bb",
+	[]interface{}{"a"},
+	[]interface{}{"b"},
+	[]interface{}{"b", "c"},
+	[]interface{}{"b", "c", "a"},
+	[]interface{}{"b", "d"},
+	[]interface{}{"b", "d", "e"},
+	map[string]interface{}{"a": 1},
+	map[string]interface{}{"a": 2},
+	map[string]interface{}{"b": 1},
+	map[string]interface{}{"b": 2},
+	map[string]interface{}{"b": 2, "c": 2},
+}
+
+func TestCompare(t *testing.T) {
+	for i := range sorted {
+		for j := range sorted {
+			want := 0
+			switch {
+			case i < j:
+				want = -1
+			case i > j:
+				want = 1
+			}
+			if got := Compare(sorted[i], sorted[j]); got != want {
+				t.Errorf("Compare(%v, %v) = %d, want %d", sorted[i], sorted[j], got, want)
+			}
+		}
+	}
+}
+
+func TestCompareStrings(t *testing.T) {
+	ascending := []string{" ", "_", "-", ",", "!", ".", "\"", "(", "[", "{", "@", "/", "&", "+", "<", "=", "~", "$", "0", "9", "a", "A", "é", "É"}
+	for i := 1; i < len(ascending); i++ {
+		if Compare(ascending[i-1], ascending[i]) != -1 {
+			t.Errorf("expected %q before %q", ascending[i-1], ascending[i])
+		}
+	}
+}
+
+func TestCompareJSON(t *testing.T) {
+	// members are compared in the order of the document and not by sorted keys
+	c, err := CompareJSON([]byte(`{"b": 1, "a": 1}`), []byte(`{"a": 1, "b": 1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c != 1 {
+		t.Errorf("expected 1 but got %d", c)
+	}
+	if _, err := CompareJSON([]byte(`{`), []byte(`1`)); err == nil {
+		t.Error("expected error for invalid JSON")
+	}
+}
+
+func TestPrefixRange(t *testing.T) {
+	start, end := PrefixRange("org1")
+	inside := [][]interface{}{{"org1"}, {"org1", 2}, {"org1", "a", "b"}}
+	for _, key := range inside {
+		if Compare(start, key) > 0 || Compare(key, end) > 0 {
+			t.Errorf("expected %v in range", key)
+		}
+	}
+	outside := [][]interface{}{{"org0", 1}, {"org2"}, {"org10"}}
+	for _, key := range outside {
+		if Compare(start, key) <= 0 && Compare(key, end) <= 0 {
+			t.Errorf("expected %v outside of range", key)
+		}
+	}
+	start, end = PrefixRange()
+	if len(start) != 0 || !reflect.DeepEqual(end, []interface{}{map[string]interface{}{}}) {
+		t.Errorf("unexpected range %v %v", start, end)
+	}
+}
+
+func TestStringPrefixRange(t *testing.T) {
+	start, end := StringPrefixRange("abc")
+	for _, key := range []string{"abc", "abcd", "abcZ", "abc~"} {
+		if Compare(start, key) > 0 || Compare(key, end) > 0 {
+			t.Errorf("expected %q in range", key)
+		}
+	}
+	for _, key := range []string{"abb", "abd", "ab"} {
+		if Compare(start, key) <= 0 && Compare(key, end) <= 0 {
+			t.Errorf("expected %q outside of range", key)
+		}
+	}
+}
+
+func TestSortRows(t *testing.T) {
+	rows := []couchdb.Row{
+		{ID: "4", Key: []interface{}{"b", 1}},
+		{ID: "2", Key: "B"},
+		{ID: "1", Key: "b"},
+		{ID: "3", Key: "b"},
+		{ID: "5", Key: nil},
+		{ID: "6", Key: 10.0},
+	}
+	SortRows(rows)
+	var ids []string
+	for _, row := range rows {
+		ids = append(ids, row.ID)
+	}
+	if want := []string{"5", "6", "1", "3", "2", "4"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("expected %v but got %v", want, ids)
+	}
+	rows = []couchdb.Row{{ID: "b"}, {ID: "B"}, {ID: "a"}}
+	SortRowsByID(rows)
+	if rows[0].ID != "B" || rows[1].ID != "a" || rows[2].ID != "b" {
+		t.Errorf("unexpected order %v", rows)
+	}
+}
+
+func TestMergeRows(t *testing.T) {
+	a := []couchdb.Row{{ID: "1", Key: "a"}, {ID: "3", Key: "b"}, {ID: "5", Key: []interface{}{"a"}}}
+	b := []couchdb.Row{{ID: "2", Key: "A"}, {ID: "4", Key: "c"}}
+	c := []couchdb.Row{{ID: "0", Key: nil}}
+	merged := MergeRows(a, b, c, nil)
+	var ids []string
+	for _, row := range merged {
+		ids = append(ids, row.ID)
+	}
+	if want := []string{"0", "1", "2", "3", "4", "5"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("expected %v but got %v", want, ids)
+	}
+}
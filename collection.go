@@ -0,0 +1,129 @@
+package couchdb
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// Collection gives typed access to the documents of a database.
+// T is usually a pointer to a struct embedding Document, e.g. *Player, or ArbitraryDoc.
+//
+//	players := couchdb.NewCollection[*Player](client, "players")
+//	player, err := players.Get(ctx, "john")
+type Collection[T CouchDoc] struct {
+	db *Database
+}
+
+// NewCollection returns a collection for documents of type T in the named database.
+func NewCollection[T CouchDoc](c *Client, name string) *Collection[T] {
+	return &Collection[T]{
+		db: &Database{
+			Client: c,
+			Name:   name,
+		},
+	}
+}
+
+// Database returns the database of the collection for everything not covered by Collection.
+func (c *Collection[T]) Database() DatabaseService {
+	return c.db
+}
+
+// ViewRow is a view row with decoded key, value and included document.
+type ViewRow[K, V any, T CouchDoc] struct {
+	ID    string `json:"id"`
+	Key   K      `json:"key"`
+	Value V      `json:"value"`
+	Doc   T      `json:"doc"`
+}
+
+// Get returns the document with the given id.
+func (c *Collection[T]) Get(ctx context.Context, id string) (T, error) {
+	var doc T
+	err := c.db.get(ctx, &doc, id)
+	return doc, err
+}
+
+// Put creates or updates the document.
+func (c *Collection[T]) Put(ctx context.Context, doc T) (*DocumentResponse, error) {
+	return c.db.PutContext(ctx, doc)
+}
+
+// Delete removes the document.
+func (c *Collection[T]) Delete(ctx context.Context, doc T) (*DocumentResponse, error) {
+	return c.db.DeleteContext(ctx, doc)
+}
+
+// List returns the documents from _all_docs. Design documents are skipped.
+func (c *Collection[T]) List(ctx context.Context, params QueryParameters) ([]T, error) {
+	includeDocs := true
+	params.IncludeDocs = &includeDocs
+	var response struct {
+		Rows []ViewRow[json.RawMessage, json.RawMessage, T] `json:"rows"`
+	}
+	if err := c.db.allDocs(ctx, &params, &response); err != nil {
+		return nil, err
+	}
+	docs := make([]T, 0, len(response.Rows))
+	for _, row := range response.Rows {
+		if strings.HasPrefix(row.ID, "_design/") || isNil(row.Doc) {
+			continue
+		}
+		docs = append(docs, row.Doc)
+	}
+	return docs, nil
+}
+
+// Query returns the documents emitted by the view of the given design document.
+func (c *Collection[T]) Query(ctx context.Context, design, view string, params QueryParameters) ([]T, error) {
+	includeDocs := true
+	params.IncludeDocs = &includeDocs
+	rows, err := QueryRows[json.RawMessage, json.RawMessage](ctx, c, design, view, params)
+	if err != nil {
+		return nil, err
+	}
+	docs := make([]T, 0, len(rows))
+	for _, row := range rows {
+		if isNil(row.Doc) {
+			continue
+		}
+		docs = append(docs, row.Doc)
+	}
+	return docs, nil
+}
+
+// Find returns the documents matching the Mango query.
+func (c *Collection[T]) Find(ctx context.Context, q FindQuery) ([]T, error) {
+	var docs []T
+	if _, err := c.db.FindContext(ctx, q, &docs); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// QueryRows executes the view of the given design document and decodes
+// keys into K, values into V and included documents into T.
+func QueryRows[K, V any, T CouchDoc](ctx context.Context, c *Collection[T], design, view string, params QueryParameters) ([]ViewRow[K, V, T], error) {
+	var response struct {
+		Rows []ViewRow[K, V, T] `json:"rows"`
+	}
+	if err := c.db.view(design).get(ctx, view, params, &response); err != nil {
+		return nil, err
+	}
+	return response.Rows, nil
+}
+
+// isNil reports whether v is nil or a nil pointer, map or slice, which are all encoded as null.
+// It detects null documents, e.g. for deleted documents in _all_docs, and unset keys of QueryParameters.
+func isNil(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
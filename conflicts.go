@@ -0,0 +1,265 @@
+package couchdb
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+)
+
+// Conflict is a document with several leaf revisions after concurrent updates,
+// usually on different replicas.
+type Conflict struct {
+	ID string
+	// Leaves are the conflicting revisions with the revision CouchDB picked as winner first.
+	Leaves []ArbitraryDoc
+}
+
+// Resolver merges the conflicting leaf revisions of a document into a single document.
+// The leaves are passed with the current winner first.
+type Resolver func(leaves []ArbitraryDoc) (ArbitraryDoc, error)
+
+// LastWriteWins returns a Resolver that keeps the leaf with the greatest value of the given field,
+// e.g. a timestamp in RFC 3339 format or a Unix time. The current winner is kept on ties.
+// Values of different types are ordered by type: missing or null values, booleans, numbers,
+// strings and all other values, so the result does not depend on the order of the leaves.
+func LastWriteWins(field string) Resolver {
+	return func(leaves []ArbitraryDoc) (ArbitraryDoc, error) {
+		latest := leaves[0]
+		for _, leaf := range leaves[1:] {
+			if later(leaf[field], latest[field]) {
+				latest = leaf
+			}
+		}
+		return latest, nil
+	}
+}
+
+// later reports whether a is greater than b, comparing the rank of their types first.
+func later(a, b interface{}) bool {
+	if ra, rb := typeRank(a), typeRank(b); ra != rb {
+		return ra > rb
+	}
+	switch a := a.(type) {
+	case bool:
+		return a && !b.(bool)
+	case float64:
+		return a > b.(float64)
+	case string:
+		return a > b.(string)
+	}
+	// arrays and objects have no order among each other
+	return false
+}
+
+// typeRank orders the types of decoded JSON values.
+func typeRank(v interface{}) int {
+	switch v.(type) {
+	case nil:
+		return 0
+	case bool:
+		return 1
+	case float64:
+		return 2
+	case string:
+		return 3
+	}
+	return 4
+}
+
+// MergeWith returns a Resolver that decodes the leaves into T and merges them with fn.
+//
+//	resolver := couchdb.MergeWith(func(leaves []*Cart) (*Cart, error) {
+//		merged := leaves[0]
+//		for _, leaf := range leaves[1:] {
+//			merged.Items = append(merged.Items, leaf.Items...)
+//		}
+//		return merged, nil
+//	})
+func MergeWith[T CouchDoc](fn func(leaves []T) (T, error)) Resolver {
+	return func(leaves []ArbitraryDoc) (ArbitraryDoc, error) {
+		typed := make([]T, len(leaves))
+		for i, leaf := range leaves {
+			b, err := json.Marshal(leaf)
+			if err != nil {
+				return nil, err
+			}
+			if err := json.Unmarshal(b, &typed[i]); err != nil {
+				return nil, err
+			}
+		}
+		merged, err := fn(typed)
+		if err != nil {
+			return nil, err
+		}
+		b, err := json.Marshal(merged)
+		if err != nil {
+			return nil, err
+		}
+		doc := ArbitraryDoc{}
+		return doc, json.Unmarshal(b, &doc)
+	}
+}
+
+// DeepMerge is a Resolver that combines the fields of all leaves.
+// Nested objects are merged recursively. When leaves have different values
+// for the same field the value of the earlier leaf is kept, so the current winner takes precedence.
+func DeepMerge(leaves []ArbitraryDoc) (ArbitraryDoc, error) {
+	merged := map[string]interface{}{}
+	for _, leaf := range leaves {
+		mergeInto(merged, leaf)
+	}
+	return merged, nil
+}
+
+// mergeInto copies all fields of src that dst does not have yet.
+func mergeInto(dst, src map[string]interface{}) {
+	for key, value := range src {
+		existing, ok := dst[key]
+		if !ok {
+			if m, isMap := value.(map[string]interface{}); isMap {
+				copied := map[string]interface{}{}
+				mergeInto(copied, m)
+				value = copied
+			}
+			dst[key] = value
+			continue
+		}
+		a, aIsMap := existing.(map[string]interface{})
+		b, bIsMap := value.(map[string]interface{})
+		if aIsMap && bIsMap {
+			mergeInto(a, b)
+		}
+	}
+}
+
+// Conflicts returns the ids of all documents with conflicts.
+// The whole _changes feed is read in pages until an empty page arrives.
+func (db *Database) Conflicts() ([]string, error) {
+	return db.ConflictsContext(context.Background())
+}
+
+// ConflictsContext is like Conflicts but includes a context.
+func (db *Database) ConflictsContext(ctx context.Context) ([]string, error) {
+	conflicts := true
+	includeDocs := true
+	limit := DefaultPageSize
+	params := ChangesQueryParameters{
+		Conflicts:   &conflicts,
+		IncludeDocs: &includeDocs,
+		Limit:       &limit,
+	}
+	changes := &Changes{Database: db}
+	ids := []string{}
+	for {
+		res, err := changes.PollContext(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		for _, change := range res.Results {
+			var doc struct {
+				Conflicts []string `json:"_conflicts"`
+			}
+			if len(change.Doc) > 0 {
+				if err := json.Unmarshal(change.Doc, &doc); err != nil {
+					return nil, err
+				}
+			}
+			if len(doc.Conflicts) > 0 {
+				ids = append(ids, change.ID)
+			}
+		}
+		// a short page is not the end of the feed, only an empty one is
+		if len(res.Results) == 0 || (params.Since != nil && res.LastSeq == *params.Since) {
+			return ids, nil
+		}
+		since := res.LastSeq
+		params.Since = &since
+	}
+}
+
+// LoadConflict loads all conflicting leaf revisions of the document.
+// Leaves is empty when the document has no conflicts.
+func (db *Database) LoadConflict(id string) (*Conflict, error) {
+	return db.LoadConflictContext(context.Background(), id)
+}
+
+// LoadConflictContext is like LoadConflict but includes a context.
+func (db *Database) LoadConflictContext(ctx context.Context, id string) (*Conflict, error) {
+	meta, err := db.GetWithOptionsContext(ctx, ArbitraryDoc{}, id, GetOptions{Conflicts: true})
+	if err != nil {
+		return nil, err
+	}
+	conflict := &Conflict{ID: id, Leaves: []ArbitraryDoc{}}
+	if len(meta.Conflicts) == 0 {
+		return conflict, nil
+	}
+	revs, err := db.GetOpenRevsContext(ctx, id, append([]string{meta.Rev}, meta.Conflicts...), GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	// the response is not sorted, so find the winner explicitly
+	for _, rev := range revs {
+		doc := ArbitraryDoc{}
+		if err := rev.Decode(doc); err != nil {
+			return nil, err
+		}
+		if rev.Rev == meta.Rev {
+			conflict.Leaves = append([]ArbitraryDoc{doc}, conflict.Leaves...)
+			continue
+		}
+		conflict.Leaves = append(conflict.Leaves, doc)
+	}
+	return conflict, nil
+}
+
+// ResolveConflict merges the conflicting revisions of the document with resolve.
+// The merged document is stored as new revision of the winner and all other leaves
+// are deleted with the same _bulk_docs request. Attachments are kept from the winner.
+// Nothing is written when the document has no conflicts.
+//
+//	res, err := db.ResolveConflict("john", couchdb.LastWriteWins("updatedAt"))
+func (db *Database) ResolveConflict(id string, resolve Resolver) (BulkResults, error) {
+	return db.ResolveConflictContext(context.Background(), id, resolve)
+}
+
+// ResolveConflictContext is like ResolveConflict but includes a context.
+func (db *Database) ResolveConflictContext(ctx context.Context, id string, resolve Resolver) (BulkResults, error) {
+	conflict, err := db.LoadConflictContext(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(conflict.Leaves) < 2 {
+		return BulkResults{}, nil
+	}
+	winner := conflict.Leaves[0]
+	merged, err := resolve(conflict.Leaves)
+	if err != nil {
+		return nil, err
+	}
+	// the merged document continues the branch of the winner
+	// and can only keep the attachment stubs of that branch
+	doc := ArbitraryDoc{}
+	for key, value := range merged {
+		if !strings.HasPrefix(key, "_") {
+			doc[key] = value
+		}
+	}
+	doc["_id"] = id
+	doc["_rev"] = winner.GetRev()
+	if attachments, ok := winner["_attachments"]; ok {
+		doc["_attachments"] = attachments
+	}
+	docs := []CouchDoc{doc}
+	for _, leaf := range conflict.Leaves[1:] {
+		docs = append(docs, ArbitraryDoc{
+			"_id":      id,
+			"_rev":     leaf.GetRev(),
+			"_deleted": true,
+		})
+	}
+	res, err := db.BulkContext(ctx, docs)
+	if err != nil {
+		return nil, err
+	}
+	return res, res.Err()
+}
@@ -2,35 +2,97 @@ package couchdb
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"mime/multipart"
+	"io"
+	"iter"
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
-
-	"github.com/google/go-querystring/query"
 )
 
 // DatabaseService is an interface for dealing with a single CouchDB database.
 type DatabaseService interface {
 	AllDocs(params *QueryParameters) (*ViewResponse, error)
+	AllDocsContext(ctx context.Context, params *QueryParameters) (*ViewResponse, error)
+	AllDocsRaw(params *QueryParameters) (*RawViewResponse, error)
+	AllDocsRawContext(ctx context.Context, params *QueryParameters) (*RawViewResponse, error)
+	AllDocsQueries(queries []QueryParameters) ([]ViewResponse, error)
+	AllDocsQueriesContext(ctx context.Context, queries []QueryParameters) ([]ViewResponse, error)
+	AllDocsRows(params QueryParameters, pageSize int) iter.Seq2[Row, error]
+	AllDocsRowsContext(ctx context.Context, params QueryParameters, pageSize int) iter.Seq2[Row, error]
 	AllDesignDocs() ([]DesignDocument, error)
+	AllDesignDocsContext(ctx context.Context) ([]DesignDocument, error)
 	Head(id string) (*http.Response, error)
+	HeadContext(ctx context.Context, id string) (*http.Response, error)
 	Get(doc CouchDoc, id string) error
+	GetContext(ctx context.Context, doc CouchDoc, id string) error
+	GetWithOptions(doc CouchDoc, id string, opts GetOptions) (*DocumentMeta, error)
+	GetWithOptionsContext(ctx context.Context, doc CouchDoc, id string, opts GetOptions) (*DocumentMeta, error)
+	GetOpenRevs(id string, revs []string, opts GetOptions) ([]OpenRev, error)
+	GetOpenRevsContext(ctx context.Context, id string, revs []string, opts GetOptions) ([]OpenRev, error)
+	Conflicts() ([]string, error)
+	ConflictsContext(ctx context.Context) ([]string, error)
+	LoadConflict(id string) (*Conflict, error)
+	LoadConflictContext(ctx context.Context, id string) (*Conflict, error)
+	ResolveConflict(id string, resolve Resolver) (BulkResults, error)
+	ResolveConflictContext(ctx context.Context, id string, resolve Resolver) (BulkResults, error)
 	Put(doc CouchDoc) (*DocumentResponse, error)
+	PutContext(ctx context.Context, doc CouchDoc) (*DocumentResponse, error)
 	Post(doc CouchDoc) (*DocumentResponse, error)
+	PostContext(ctx context.Context, doc CouchDoc) (*DocumentResponse, error)
 	Delete(doc CouchDoc) (*DocumentResponse, error)
+	DeleteContext(ctx context.Context, doc CouchDoc) (*DocumentResponse, error)
+	Copy(srcID, dstID string, opts CopyOptions) (*DocumentResponse, error)
+	CopyContext(ctx context.Context, srcID, dstID string, opts CopyOptions) (*DocumentResponse, error)
 	PutAttachment(doc CouchDoc, path string) (*DocumentResponse, error)
-	Bulk(docs []CouchDoc) ([]DocumentResponse, error)
+	PutAttachmentContext(ctx context.Context, doc CouchDoc, path string) (*DocumentResponse, error)
+	PutAttachmentReader(doc CouchDoc, name, contentType string, r io.Reader) (*DocumentResponse, error)
+	PutAttachmentReaderContext(ctx context.Context, doc CouchDoc, name, contentType string, r io.Reader) (*DocumentResponse, error)
+	GetAttachment(id, name string) (*AttachmentReader, error)
+	GetAttachmentContext(ctx context.Context, id, name string) (*AttachmentReader, error)
+	PutAttachmentIfChanged(doc CouchDoc, name, contentType string, r io.ReadSeeker) (*DocumentResponse, bool, error)
+	PutAttachmentIfChangedContext(ctx context.Context, doc CouchDoc, name, contentType string, r io.ReadSeeker) (*DocumentResponse, bool, error)
+	DeleteAttachment(doc CouchDoc, name string) (*DocumentResponse, error)
+	DeleteAttachmentContext(ctx context.Context, doc CouchDoc, name string) (*DocumentResponse, error)
+	PutWithAttachments(doc CouchDoc, attachments ...AttachmentUpload) (*DocumentResponse, error)
+	PutWithAttachmentsContext(ctx context.Context, doc CouchDoc, attachments ...AttachmentUpload) (*DocumentResponse, error)
+	GetWithAttachments(doc CouchDoc, id string) error
+	GetWithAttachmentsContext(ctx context.Context, doc CouchDoc, id string) error
+	Bulk(docs []CouchDoc) (BulkResults, error)
+	BulkContext(ctx context.Context, docs []CouchDoc) (BulkResults, error)
+	BulkWithOptions(docs []CouchDoc, opts BulkOptions) (BulkResults, error)
+	BulkWithOptionsContext(ctx context.Context, docs []CouchDoc, opts BulkOptions) (BulkResults, error)
+	BulkGet(refs []BulkGetRef, opts BulkGetOptions) ([]BulkGetResult, error)
+	BulkGetContext(ctx context.Context, refs []BulkGetRef, opts BulkGetOptions) ([]BulkGetResult, error)
 	Purge(req map[string][]string) (*PurgeResponse, error)
+	PurgeContext(ctx context.Context, req map[string][]string) (*PurgeResponse, error)
 	GetSecurity() (*SecurityDocument, error)
+	GetSecurityContext(ctx context.Context) (*SecurityDocument, error)
 	PutSecurity(secDoc SecurityDocument) (*DatabaseResponse, error)
+	PutSecurityContext(ctx context.Context, secDoc SecurityDocument) (*DatabaseResponse, error)
+	Find(q FindQuery, docs interface{}) (*FindResponse, error)
+	FindContext(ctx context.Context, q FindQuery, docs interface{}) (*FindResponse, error)
+	CreateIndex(index Index) (*IndexResponse, error)
+	CreateIndexContext(ctx context.Context, index Index) (*IndexResponse, error)
+	Indexes() ([]Index, error)
+	IndexesContext(ctx context.Context) ([]Index, error)
+	DeleteIndex(index Index) (*DatabaseResponse, error)
+	DeleteIndexContext(ctx context.Context, index Index) (*DatabaseResponse, error)
+	Explain(q FindQuery) (*ExplainResponse, error)
+	ExplainContext(ctx context.Context, q FindQuery) (*ExplainResponse, error)
+	Update(id string, doc CouchDoc, fn func(CouchDoc) error) (*DocumentResponse, error)
+	UpdateContext(ctx context.Context, id string, doc CouchDoc, fn func(CouchDoc) error) (*DocumentResponse, error)
+	Upsert(id string, doc CouchDoc, fn func(CouchDoc) error) (*DocumentResponse, error)
+	UpsertContext(ctx context.Context, id string, doc CouchDoc, fn func(CouchDoc) error) (*DocumentResponse, error)
 	View(name string) ViewService
 	Changes() ChangesService
 	Seed([]DesignDocument) error
+	SeedContext(ctx context.Context, cache []DesignDocument) error
 }
 
 // Database performs actions on certain database
@@ -42,15 +104,18 @@ type Database struct {
 // AllDesignDocs returns all design documents from database.
 // http://stackoverflow.com/questions/2814352/get-all-design-documents-in-couchdb
 func (db *Database) AllDesignDocs() ([]DesignDocument, error) {
-	startKey := fmt.Sprintf("%q", "_design/")
-	endKey := fmt.Sprintf("%q", "_design0")
+	return db.AllDesignDocsContext(context.Background())
+}
+
+// AllDesignDocsContext is like AllDesignDocs but includes a context.
+func (db *Database) AllDesignDocsContext(ctx context.Context) ([]DesignDocument, error) {
 	includeDocs := true
 	q := QueryParameters{
-		StartKey:    &startKey,
-		EndKey:      &endKey,
+		StartKey:    "_design/",
+		EndKey:      "_design0",
 		IncludeDocs: &includeDocs,
 	}
-	res, err := db.AllDocs(&q)
+	res, err := db.AllDocsContext(ctx, &q)
 	if err != nil {
 		return nil, err
 	}
@@ -69,24 +134,58 @@ func (db *Database) AllDesignDocs() ([]DesignDocument, error) {
 // AllDocs returns all documents in selected database.
 // http://docs.couchdb.org/en/latest/api/database/bulk-api.html
 func (db *Database) AllDocs(params *QueryParameters) (*ViewResponse, error) {
-	q, err := query.Values(params)
+	return db.AllDocsContext(context.Background(), params)
+}
+
+// AllDocsContext is like AllDocs but includes a context.
+func (db *Database) AllDocsContext(ctx context.Context, params *QueryParameters) (*ViewResponse, error) {
+	var response ViewResponse
+	return &response, db.allDocs(ctx, params, &response)
+}
+
+// AllDocsRaw is like AllDocs but keeps keys, values and documents encoded.
+// Rows of missing keys have an Error, e.g. when documents are requested with Keys.
+func (db *Database) AllDocsRaw(params *QueryParameters) (*RawViewResponse, error) {
+	return db.AllDocsRawContext(context.Background(), params)
+}
+
+// AllDocsRawContext is like AllDocsRaw but includes a context.
+func (db *Database) AllDocsRawContext(ctx context.Context, params *QueryParameters) (*RawViewResponse, error) {
+	res, err := db.requestAllDocs(ctx, params)
 	if err != nil {
 		return nil, err
 	}
-	u := fmt.Sprintf("%s/_all_docs?%s", url.PathEscape(db.Name), q.Encode())
-	res, err := db.Client.Request(http.MethodGet, u, nil, "")
+	return decodeRawView(res)
+}
+
+// allDocs requests all documents and decodes the response into v.
+func (db *Database) allDocs(ctx context.Context, params *QueryParameters, v interface{}) error {
+	res, err := db.requestAllDocs(ctx, params)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer res.Body.Close()
-	var response ViewResponse
-	return &response, json.NewDecoder(res.Body).Decode(&response)
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+func (db *Database) requestAllDocs(ctx context.Context, params *QueryParameters) (*http.Response, error) {
+	q, err := params.values()
+	if err != nil {
+		return nil, err
+	}
+	u := fmt.Sprintf("%s/_all_docs?%s", url.PathEscape(db.Name), q.Encode())
+	return db.Client.RequestContext(ctx, http.MethodGet, u, nil, "")
 }
 
 // Head request.
 func (db *Database) Head(id string) (*http.Response, error) {
+	return db.HeadContext(context.Background(), id)
+}
+
+// HeadContext is like Head but includes a context.
+func (db *Database) HeadContext(ctx context.Context, id string) (*http.Response, error) {
 	u := fmt.Sprintf("%s/%s", url.PathEscape(db.Name), url.PathEscape(id))
-	body, err := db.Client.Request(http.MethodHead, u, nil, "")
+	body, err := db.Client.RequestContext(ctx, http.MethodHead, u, nil, "")
 	if err != nil {
 		return nil, err
 	}
@@ -95,23 +194,52 @@ func (db *Database) Head(id string) (*http.Response, error) {
 
 // Get document.
 func (db *Database) Get(doc CouchDoc, id string) error {
+	return db.GetContext(context.Background(), doc, id)
+}
+
+// GetContext is like Get but includes a context.
+func (db *Database) GetContext(ctx context.Context, doc CouchDoc, id string) error {
+	return db.get(ctx, decodeTarget(doc), id)
+}
+
+// get requests a single document and decodes it into v.
+func (db *Database) get(ctx context.Context, v interface{}, id string) error {
 	u := fmt.Sprintf("%s/%s", url.PathEscape(db.Name), url.PathEscape(id))
-	res, err := db.Client.Request(http.MethodGet, u, nil, "application/json")
+	res, err := db.Client.RequestContext(ctx, http.MethodGet, u, nil, "application/json")
 	if err != nil {
 		return err
 	}
 	defer res.Body.Close()
-	return json.NewDecoder(res.Body).Decode(doc)
+	return json.NewDecoder(res.Body).Decode(v)
 }
 
 // Put document.
 func (db *Database) Put(doc CouchDoc) (*DocumentResponse, error) {
-	u := fmt.Sprintf("%s/%s", url.PathEscape(db.Name), url.PathEscape(doc.GetID()))
+	return db.PutContext(context.Background(), doc)
+}
+
+// PutContext is like Put but includes a context.
+func (db *Database) PutContext(ctx context.Context, doc CouchDoc) (*DocumentResponse, error) {
+	return db.put(ctx, doc.GetID(), doc)
+}
+
+// put stores the document under the given id.
+func (db *Database) put(ctx context.Context, id string, doc CouchDoc) (*DocumentResponse, error) {
+	// updating a given revision cannot be applied twice
+	if doc.GetRev() != "" {
+		ctx = idempotent(ctx)
+	}
+	return db.putOnce(ctx, id, doc)
+}
+
+// putOnce is like put but leaves retrying to the context, so it is only retried when marked as idempotent.
+func (db *Database) putOnce(ctx context.Context, id string, doc CouchDoc) (*DocumentResponse, error) {
+	u := fmt.Sprintf("%s/%s", url.PathEscape(db.Name), url.PathEscape(id))
 	var b bytes.Buffer
 	if err := json.NewEncoder(&b).Encode(doc); err != nil {
 		return nil, err
 	}
-	res, err := db.Client.Request(http.MethodPut, u, &b, "application/json")
+	res, err := db.Client.RequestContext(ctx, http.MethodPut, u, &b, "application/json")
 	if err != nil {
 		return nil, err
 	}
@@ -122,11 +250,16 @@ func (db *Database) Put(doc CouchDoc) (*DocumentResponse, error) {
 
 // Post document.
 func (db *Database) Post(doc CouchDoc) (*DocumentResponse, error) {
+	return db.PostContext(context.Background(), doc)
+}
+
+// PostContext is like Post but includes a context.
+func (db *Database) PostContext(ctx context.Context, doc CouchDoc) (*DocumentResponse, error) {
 	var b bytes.Buffer
 	if err := json.NewEncoder(&b).Encode(doc); err != nil {
 		return nil, err
 	}
-	res, err := db.Client.Request(http.MethodPost, url.PathEscape(db.Name), &b, "application/json")
+	res, err := db.Client.RequestContext(ctx, http.MethodPost, url.PathEscape(db.Name), &b, "application/json")
 	if err != nil {
 		return nil, err
 	}
@@ -137,8 +270,16 @@ func (db *Database) Post(doc CouchDoc) (*DocumentResponse, error) {
 
 // Delete document.
 func (db *Database) Delete(doc CouchDoc) (*DocumentResponse, error) {
+	return db.DeleteContext(context.Background(), doc)
+}
+
+// DeleteContext is like Delete but includes a context.
+func (db *Database) DeleteContext(ctx context.Context, doc CouchDoc) (*DocumentResponse, error) {
+	if doc.GetRev() != "" {
+		ctx = idempotent(ctx)
+	}
 	u := fmt.Sprintf("%s/%s?rev=%s", url.PathEscape(db.Name), url.PathEscape(doc.GetID()), doc.GetRev())
-	res, err := db.Client.Request(http.MethodDelete, u, nil, "application/json")
+	res, err := db.Client.RequestContext(ctx, http.MethodDelete, u, nil, "application/json")
 	if err != nil {
 		return nil, err
 	}
@@ -147,81 +288,121 @@ func (db *Database) Delete(doc CouchDoc) (*DocumentResponse, error) {
 	return &response, json.NewDecoder(res.Body).Decode(&response)
 }
 
-// PutAttachment adds attachment to document
-func (db *Database) PutAttachment(doc CouchDoc, path string) (*DocumentResponse, error) {
-
-	// target url
-	u := fmt.Sprintf("%s/%s", url.PathEscape(db.Name), url.PathEscape(doc.GetID()))
-
-	// get file from disk
-	file, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-
-	// create new writer
-	buffer := bytes.Buffer{}
-	writer := multipart.NewWriter(&buffer)
+// CopyOptions are the optional parameters of Copy.
+type CopyOptions struct {
+	// Rev is the revision of the source document to copy. Defaults to the latest one.
+	Rev string
+	// DestinationRev is the current revision of the destination document
+	// and must be set to overwrite an existing document.
+	DestinationRev string
+}
 
-	// create first "application/json" document part
-	document := Document{
-		ID:  doc.GetID(),
-		Rev: doc.GetRev(),
-	}
-	err = writeJSON(&document, writer, file)
-	if err != nil {
-		return nil, err
-	}
+// Copy duplicates a document including its attachments on the server
+// without downloading it, e.g. to clone templates with large attachments.
+func (db *Database) Copy(srcID, dstID string, opts CopyOptions) (*DocumentResponse, error) {
+	return db.CopyContext(context.Background(), srcID, dstID, opts)
+}
 
-	// write actual file content to multipart message
-	err = writeMultipart(writer, file)
+// CopyContext is like Copy but includes a context.
+func (db *Database) CopyContext(ctx context.Context, srcID, dstID string, opts CopyOptions) (*DocumentResponse, error) {
+	u := fmt.Sprintf("%s/%s", url.PathEscape(db.Name), url.PathEscape(srcID))
+	if opts.Rev != "" {
+		u += "?" + url.Values{"rev": {opts.Rev}}.Encode()
+	}
+	// CouchDB takes the destination id as it is without decoding it
+	destination := dstID
+	if opts.DestinationRev != "" {
+		destination += "?rev=" + opts.DestinationRev
+		// overwriting a given revision cannot be applied twice
+		ctx = idempotent(ctx)
+	}
+	header := http.Header{}
+	header.Set("Destination", destination)
+	res, err := db.Client.request(ctx, "COPY", u, nil, header)
 	if err != nil {
 		return nil, err
 	}
+	defer res.Body.Close()
+	var response DocumentResponse
+	return &response, json.NewDecoder(res.Body).Decode(&response)
+}
 
-	// finish multipart message and write trailing boundary
-	err = writer.Close()
-	if err != nil {
-		return nil, err
-	}
+// PutAttachment adds attachment to document
+func (db *Database) PutAttachment(doc CouchDoc, path string) (*DocumentResponse, error) {
+	return db.PutAttachmentContext(context.Background(), doc, path)
+}
 
-	// create http request
-	contentType := fmt.Sprintf("multipart/related; boundary=%q", writer.Boundary())
-	res, err := db.Client.Request(http.MethodPut, u, &buffer, contentType)
+// PutAttachmentContext is like PutAttachment but includes a context.
+func (db *Database) PutAttachmentContext(ctx context.Context, doc CouchDoc, path string) (*DocumentResponse, error) {
+	// get file from disk
+	file, err := os.Open(path)
 	if err != nil {
 		return nil, err
 	}
-	defer res.Body.Close()
-	var response DocumentResponse
-	return &response, json.NewDecoder(res.Body).Decode(&response)
+	defer file.Close()
+	return db.PutWithAttachmentsContext(ctx, doc, AttachmentUpload{
+		Name:        filepath.Base(path),
+		ContentType: mimeType(path),
+		Body:        file,
+	})
 }
 
 // Bulk allows to create and update multiple documents
 // at the same time within a single request. The basic operation is similar to
 // creating or updating a single document, except that you batch
 // the document structure and information.
-func (db *Database) Bulk(docs []CouchDoc) ([]DocumentResponse, error) {
+// A successful request can still contain failures for single documents,
+// use BulkResults.Err to check for them.
+func (db *Database) Bulk(docs []CouchDoc) (BulkResults, error) {
+	return db.BulkContext(context.Background(), docs)
+}
+
+// BulkContext is like Bulk but includes a context.
+func (db *Database) BulkContext(ctx context.Context, docs []CouchDoc) (BulkResults, error) {
+	return db.BulkWithOptionsContext(ctx, docs, BulkOptions{})
+}
+
+// BulkWithOptions is like Bulk but allows to set the all_or_nothing and new_edits options.
+//
+// Restore a backup with the original revisions.
+//
+//	newEdits := false
+//	res, err := db.BulkWithOptions(docs, couchdb.BulkOptions{NewEdits: &newEdits})
+func (db *Database) BulkWithOptions(docs []CouchDoc, opts BulkOptions) (BulkResults, error) {
+	return db.BulkWithOptionsContext(context.Background(), docs, opts)
+}
+
+// BulkWithOptionsContext is like BulkWithOptions but includes a context.
+func (db *Database) BulkWithOptionsContext(ctx context.Context, docs []CouchDoc, opts BulkOptions) (BulkResults, error) {
 	bulk := BulkDoc{
-		Docs: docs,
+		AllOrNothing: opts.AllOrNothing,
+		NewEdits:     opts.NewEdits,
+		Docs:         docs,
+	}
+	// writing given revisions has the same result no matter how often it is done
+	if opts.NewEdits != nil && !*opts.NewEdits {
+		ctx = idempotent(ctx)
 	}
 	u := fmt.Sprintf("%s/_bulk_docs", url.PathEscape(db.Name))
 	var b bytes.Buffer
 	if err := json.NewEncoder(&b).Encode(bulk); err != nil {
 		return nil, err
 	}
-	res, err := db.Client.Request(http.MethodPost, u, &b, "application/json")
+	res, err := db.Client.RequestContext(ctx, http.MethodPost, u, &b, "application/json")
 	if err != nil {
 		return nil, err
 	}
 	defer res.Body.Close()
-	response := []DocumentResponse{}
+	response := BulkResults{}
 	return response, json.NewDecoder(res.Body).Decode(&response)
-
 }
 
 // View returns view for given name.
 func (db *Database) View(name string) ViewService {
+	return db.view(name)
+}
+
+func (db *Database) view(name string) *View {
 	u := fmt.Sprintf("%s/_design/%s/", url.PathEscape(db.Name), url.PathEscape(name))
 	return &View{
 		URL:    u,
@@ -246,11 +427,16 @@ type PurgeResponse struct {
 //
 // http://docs.couchdb.org/en/1.6.1/api/database/misc.html
 func (db *Database) Purge(req map[string][]string) (*PurgeResponse, error) {
+	return db.PurgeContext(context.Background(), req)
+}
+
+// PurgeContext is like Purge but includes a context.
+func (db *Database) PurgeContext(ctx context.Context, req map[string][]string) (*PurgeResponse, error) {
 	var b bytes.Buffer
 	if err := json.NewEncoder(&b).Encode(req); err != nil {
 		return nil, err
 	}
-	res, err := db.Client.Request(http.MethodPost, url.PathEscape(db.Name)+"/_purge", &b, "application/json")
+	res, err := db.Client.RequestContext(ctx, http.MethodPost, url.PathEscape(db.Name)+"/_purge", &b, "application/json")
 	if err != nil {
 		return nil, err
 	}
@@ -274,8 +460,13 @@ type SecurityDocument struct {
 // GetSecurity returns security document.
 // http://docs.couchdb.org/en/latest/api/database/security.html
 func (db *Database) GetSecurity() (*SecurityDocument, error) {
+	return db.GetSecurityContext(context.Background())
+}
+
+// GetSecurityContext is like GetSecurity but includes a context.
+func (db *Database) GetSecurityContext(ctx context.Context) (*SecurityDocument, error) {
 	u := fmt.Sprintf("%s/_security", url.PathEscape(db.Name))
-	res, err := db.Client.Request(http.MethodGet, u, nil, "application/json")
+	res, err := db.Client.RequestContext(ctx, http.MethodGet, u, nil, "application/json")
 	if err != nil {
 		return nil, err
 	}
@@ -287,12 +478,17 @@ func (db *Database) GetSecurity() (*SecurityDocument, error) {
 // PutSecurity sets the security object for the given database.
 // http://docs.couchdb.org/en/latest/api/database/security.html#put--db-_security
 func (db *Database) PutSecurity(secDoc SecurityDocument) (*DatabaseResponse, error) {
+	return db.PutSecurityContext(context.Background(), secDoc)
+}
+
+// PutSecurityContext is like PutSecurity but includes a context.
+func (db *Database) PutSecurityContext(ctx context.Context, secDoc SecurityDocument) (*DatabaseResponse, error) {
 	u := fmt.Sprintf("%s/_security", url.PathEscape(db.Name))
 	var b bytes.Buffer
 	if err := json.NewEncoder(&b).Encode(secDoc); err != nil {
 		return nil, err
 	}
-	res, err := db.Client.Request(http.MethodPut, u, &b, "application/json")
+	res, err := db.Client.RequestContext(idempotent(ctx), http.MethodPut, u, &b, "application/json")
 	if err != nil {
 		return nil, err
 	}
@@ -303,15 +499,20 @@ func (db *Database) PutSecurity(secDoc SecurityDocument) (*DatabaseResponse, err
 
 // Seed makes sure all your design documents are up to date.
 func (db *Database) Seed(cache []DesignDocument) error {
+	return db.SeedContext(context.Background(), cache)
+}
+
+// SeedContext is like Seed but includes a context.
+func (db *Database) SeedContext(ctx context.Context, cache []DesignDocument) error {
 	// query all docs to get all design documents
-	designDocs, err := db.AllDesignDocs()
+	designDocs, err := db.AllDesignDocsContext(ctx)
 	if err != nil {
 		return err
 	}
 	difference := diff(cache, designDocs)
 	// remove all deletions
 	for _, doc := range difference.deletions {
-		if _, err := db.Delete(&doc); err != nil {
+		if _, err := db.DeleteContext(ctx, &doc); err != nil {
 			return err
 		}
 	}
@@ -319,18 +520,18 @@ func (db *Database) Seed(cache []DesignDocument) error {
 	for _, doc := range difference.changes {
 		// get design document first to get current revision
 		var old DesignDocument
-		if err := db.Get(&old, doc.ID); err != nil {
+		if err := db.GetContext(ctx, &old, doc.ID); err != nil {
 			return err
 		}
 		// update document with new version
 		doc.Rev = old.Rev
-		if _, err := db.Put(&doc); err != nil {
+		if _, err := db.PutContext(ctx, &doc); err != nil {
 			return err
 		}
 	}
 	// add all additions
 	for _, doc := range difference.additions {
-		if _, err := db.Put(&doc); err != nil {
+		if _, err := db.PutContext(ctx, &doc); err != nil {
 			return err
 		}
 	}
@@ -357,9 +558,9 @@ func diff(cache, db []DesignDocument) difference {
 		for _, d := range db {
 			if d.ID == c.ID {
 				exists = true
-				// check for different map/reduce and language
+				// check for different functions and language
 				// do not check for different revision
-				if !reflect.DeepEqual(c.Views, d.Views) {
+				if !sameContent(c, d) {
 					existsButDifferent = true
 				}
 			}
@@ -386,3 +587,26 @@ func diff(cache, db []DesignDocument) difference {
 	}
 	return di
 }
+
+// sameContent reports whether both design documents have the same language and functions.
+// The documents are compared in their decoded JSON encoding, so empty and missing fields are equal
+// and rewrites decoded from CouchDB match the DesignDocumentRewrite values they were stored from
+// regardless of the order of their fields.
+func sameContent(a, b DesignDocument) bool {
+	a.Document = Document{}
+	b.Document = Document{}
+	va, errA := normalize(a)
+	vb, errB := normalize(b)
+	return errA == nil && errB == nil && reflect.DeepEqual(va, vb)
+}
+
+// normalize decodes the JSON encoding of v into maps, slices and basic values.
+func normalize(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var n interface{}
+	err = json.Unmarshal(data, &n)
+	return n, err
+}
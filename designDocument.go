@@ -11,6 +11,14 @@ type DesignDocument struct {
 	Language string                        `json:"language,omitempty"`
 	Views    map[string]DesignDocumentView `json:"views,omitempty"`
 	Filters  map[string]string             `json:"filters,omitempty"`
+	Shows    map[string]string             `json:"shows,omitempty"`
+	Lists    map[string]string             `json:"lists,omitempty"`
+	Updates  map[string]string             `json:"updates,omitempty"`
+	// ValidateDocUpdate is a function that rejects invalid documents before they are stored.
+	ValidateDocUpdate string `json:"validate_doc_update,omitempty"`
+	// Rewrites is either a []DesignDocumentRewrite or a string with a rewrite function.
+	// Decoded design documents hold a []interface{} or a string.
+	Rewrites interface{} `json:"rewrites,omitempty"`
 }
 
 // Name returns design document name without the "_design/" prefix
@@ -23,3 +31,11 @@ type DesignDocumentView struct {
 	Map    string `json:"map,omitempty"`
 	Reduce string `json:"reduce,omitempty"`
 }
+
+// DesignDocumentRewrite is a rule of the _rewrite handler.
+type DesignDocumentRewrite struct {
+	From   string                 `json:"from"`
+	To     string                 `json:"to"`
+	Method string                 `json:"method,omitempty"`
+	Query  map[string]interface{} `json:"query,omitempty"`
+}
@@ -1,5 +1,7 @@
 package couchdb
 
+import "reflect"
+
 // CouchDoc describes interface for every couchdb document.
 type CouchDoc interface {
 	GetID() string
@@ -27,6 +29,8 @@ type Attachment struct {
 	RevPos        float64 `json:"revpos,omitempty"`
 	Stub          bool    `json:"stub,omitempty"`
 	Follows       bool    `json:"follows,omitempty"`
+	// Content is the content of the attachment loaded by Database.GetWithAttachments.
+	Content []byte `json:"-"`
 }
 
 // GetID returns document id
@@ -39,6 +43,11 @@ func (d *Document) GetRev() string {
 	return d.Rev
 }
 
+// attachments returns the attachments so they can be filled by Database.GetWithAttachments.
+func (d *Document) attachments() map[string]Attachment {
+	return d.Attachments
+}
+
 type ArbitraryDoc map[string]interface{}
 
 func (a ArbitraryDoc) GetID() string {
@@ -58,3 +67,26 @@ func (a ArbitraryDoc) GetRev() string {
 	}
 	return ""
 }
+
+// decodeTarget returns the value a document is decoded into.
+// ArbitraryDoc is a map and must be passed as pointer to be decoded into.
+func decodeTarget(doc CouchDoc) interface{} {
+	if a, ok := doc.(ArbitraryDoc); ok {
+		return &a
+	}
+	return doc
+}
+
+// resetDoc clears the document so the next decoding does not keep stale fields.
+func resetDoc(doc CouchDoc) {
+	if a, ok := doc.(ArbitraryDoc); ok {
+		for key := range a {
+			delete(a, key)
+		}
+		return
+	}
+	v := reflect.ValueOf(doc)
+	if v.Kind() == reflect.Ptr && !v.IsNil() {
+		v.Elem().Set(reflect.Zero(v.Elem().Type()))
+	}
+}
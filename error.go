@@ -1,6 +1,25 @@
 package couchdb
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Sentinel errors for common CouchDB failures. Use errors.Is to check an error returned by the client.
+//
+//	if errors.Is(err, couchdb.ErrNotFound) {
+//		// document or database does not exist
+//	}
+var (
+	ErrNotFound           = errors.New("couchdb: not found")
+	ErrConflict           = errors.New("couchdb: conflict")
+	ErrUnauthorized       = errors.New("couchdb: unauthorized")
+	ErrForbidden          = errors.New("couchdb: forbidden")
+	ErrPreconditionFailed = errors.New("couchdb: precondition failed")
+	ErrFileExists         = errors.New("couchdb: file exists")
+	ErrTooLarge           = errors.New("couchdb: request entity too large")
+)
 
 // Error describes CouchDB error.
 type Error struct {
@@ -9,6 +28,8 @@ type Error struct {
 	StatusCode int
 	Type       string `json:"error"`
 	Reason     string
+	// Body is the raw response body, e.g. an HTML page from a proxy that is no CouchDB JSON error.
+	Body []byte `json:"-"`
 }
 
 func (e *Error) Error() string {
@@ -21,3 +42,25 @@ func (e *Error) Error() string {
 		e.Reason,
 	)
 }
+
+// Is reports whether the error matches one of the sentinel errors.
+// It makes errors.Is work with *Error.
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrPreconditionFailed:
+		return e.StatusCode == http.StatusPreconditionFailed
+	case ErrFileExists:
+		return e.Type == "file_exists"
+	case ErrTooLarge:
+		return e.StatusCode == http.StatusRequestEntityTooLarge
+	}
+	return false
+}
@@ -0,0 +1,252 @@
+package couchdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// FindQuery describes POST /db/_find request object.
+// http://docs.couchdb.org/en/latest/api/database/find.html#db-find
+type FindQuery struct {
+	Selector       Selector `json:"selector"`
+	Fields         []string `json:"fields,omitempty"`
+	Sort           []Sort   `json:"sort,omitempty"`
+	Limit          *int     `json:"limit,omitempty"`
+	Skip           *int     `json:"skip,omitempty"`
+	Bookmark       string   `json:"bookmark,omitempty"`
+	UseIndex       []string `json:"use_index,omitempty"`
+	Conflicts      bool     `json:"conflicts,omitempty"`
+	R              *int     `json:"r,omitempty"`
+	Update         *bool    `json:"update,omitempty"`
+	Stable         *bool    `json:"stable,omitempty"`
+	ExecutionStats bool     `json:"execution_stats,omitempty"`
+}
+
+// Sort is a field with sort direction used in FindQuery and index definitions.
+type Sort struct {
+	Field string
+	Desc  bool
+}
+
+// Asc sorts by field in ascending order.
+func Asc(field string) Sort {
+	return Sort{Field: field}
+}
+
+// Desc sorts by field in descending order.
+func Desc(field string) Sort {
+	return Sort{Field: field, Desc: true}
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+//
+// https://golang.org/pkg/encoding/json/#Marshaler
+func (s Sort) MarshalJSON() ([]byte, error) {
+	direction := "asc"
+	if s.Desc {
+		direction = "desc"
+	}
+	return json.Marshal(map[string]string{s.Field: direction})
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+// Fields can either be plain strings or objects with a direction.
+//
+// https://golang.org/pkg/encoding/json/#Unmarshaler
+func (s *Sort) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*s = Sort{Field: name}
+		return nil
+	}
+	var m map[string]string
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	for field, direction := range m {
+		*s = Sort{Field: field, Desc: direction == "desc"}
+	}
+	return nil
+}
+
+// FindResponse is response from POST request to the _find URL.
+// The documents are decoded into the slice passed to Find.
+type FindResponse struct {
+	Warning        string          `json:"warning,omitempty"`
+	Bookmark       string          `json:"bookmark,omitempty"`
+	ExecutionStats *ExecutionStats `json:"execution_stats,omitempty"`
+}
+
+// ExecutionStats has information about the query execution when requested with FindQuery.ExecutionStats.
+type ExecutionStats struct {
+	TotalKeysExamined       int     `json:"total_keys_examined"`
+	TotalDocsExamined       int     `json:"total_docs_examined"`
+	TotalQuorumDocsExamined int     `json:"total_quorum_docs_examined"`
+	ResultsReturned         int     `json:"results_returned"`
+	ExecutionTimeMs         float64 `json:"execution_time_ms"`
+}
+
+// Index describes a Mango index.
+// http://docs.couchdb.org/en/latest/api/database/find.html#db-index
+type Index struct {
+	DesignDoc string          `json:"ddoc,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Type      string          `json:"type,omitempty"`
+	Def       IndexDefinition `json:"def"`
+}
+
+// IndexDefinition holds the indexed fields and an optional partial filter.
+type IndexDefinition struct {
+	Fields                []Sort   `json:"fields"`
+	PartialFilterSelector Selector `json:"partial_filter_selector,omitempty"`
+}
+
+// IndexResponse is response from POST request to the _index URL.
+type IndexResponse struct {
+	Result string `json:"result"`
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+}
+
+// ExplainResponse describes which index CouchDB would use for a query.
+// http://docs.couchdb.org/en/latest/api/database/find.html#db-explain
+type ExplainResponse struct {
+	DBName   string                 `json:"dbname"`
+	Index    Index                  `json:"index"`
+	Selector map[string]interface{} `json:"selector"`
+	Opts     map[string]interface{} `json:"opts"`
+	Limit    int                    `json:"limit"`
+	Skip     int                    `json:"skip"`
+	Fields   interface{}            `json:"fields"`
+	Range    map[string]interface{} `json:"range"`
+}
+
+// Find returns all documents matching the query.
+// docs must be a pointer to a slice, e.g. *[]Player, and receives the found documents.
+func (db *Database) Find(q FindQuery, docs interface{}) (*FindResponse, error) {
+	return db.FindContext(context.Background(), q, docs)
+}
+
+// FindContext is like Find but includes a context.
+func (db *Database) FindContext(ctx context.Context, q FindQuery, docs interface{}) (*FindResponse, error) {
+	if q.Selector == nil {
+		q.Selector = Selector{}
+	}
+	u := fmt.Sprintf("%s/_find", url.PathEscape(db.Name))
+	var b bytes.Buffer
+	if err := json.NewEncoder(&b).Encode(q); err != nil {
+		return nil, err
+	}
+	res, err := db.Client.RequestContext(idempotent(ctx), http.MethodPost, u, &b, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	response := &FindResponse{}
+	body := struct {
+		*FindResponse
+		Docs interface{} `json:"docs"`
+	}{response, docs}
+	return response, json.NewDecoder(res.Body).Decode(&body)
+}
+
+// CreateIndex creates a Mango index.
+func (db *Database) CreateIndex(index Index) (*IndexResponse, error) {
+	return db.CreateIndexContext(context.Background(), index)
+}
+
+// CreateIndexContext is like CreateIndex but includes a context.
+func (db *Database) CreateIndexContext(ctx context.Context, index Index) (*IndexResponse, error) {
+	req := struct {
+		Index     IndexDefinition `json:"index"`
+		DesignDoc string          `json:"ddoc,omitempty"`
+		Name      string          `json:"name,omitempty"`
+		Type      string          `json:"type,omitempty"`
+	}{index.Def, strings.TrimPrefix(index.DesignDoc, "_design/"), index.Name, index.Type}
+	u := fmt.Sprintf("%s/_index", url.PathEscape(db.Name))
+	var b bytes.Buffer
+	if err := json.NewEncoder(&b).Encode(req); err != nil {
+		return nil, err
+	}
+	res, err := db.Client.RequestContext(ctx, http.MethodPost, u, &b, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	response := &IndexResponse{}
+	return response, json.NewDecoder(res.Body).Decode(response)
+}
+
+// Indexes returns all Mango indexes of the database including the special _all_docs index.
+func (db *Database) Indexes() ([]Index, error) {
+	return db.IndexesContext(context.Background())
+}
+
+// IndexesContext is like Indexes but includes a context.
+func (db *Database) IndexesContext(ctx context.Context) ([]Index, error) {
+	u := fmt.Sprintf("%s/_index", url.PathEscape(db.Name))
+	res, err := db.Client.RequestContext(ctx, http.MethodGet, u, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	response := struct {
+		Indexes []Index `json:"indexes"`
+	}{}
+	return response.Indexes, json.NewDecoder(res.Body).Decode(&response)
+}
+
+// DeleteIndex removes a Mango index.
+func (db *Database) DeleteIndex(index Index) (*DatabaseResponse, error) {
+	return db.DeleteIndexContext(context.Background(), index)
+}
+
+// DeleteIndexContext is like DeleteIndex but includes a context.
+func (db *Database) DeleteIndexContext(ctx context.Context, index Index) (*DatabaseResponse, error) {
+	typ := index.Type
+	if typ == "" {
+		typ = "json"
+	}
+	u := fmt.Sprintf("%s/_index/%s/%s/%s",
+		url.PathEscape(db.Name),
+		url.PathEscape(strings.TrimPrefix(index.DesignDoc, "_design/")),
+		url.PathEscape(typ),
+		url.PathEscape(index.Name),
+	)
+	res, err := db.Client.RequestContext(ctx, http.MethodDelete, u, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	response := &DatabaseResponse{}
+	return response, json.NewDecoder(res.Body).Decode(response)
+}
+
+// Explain shows which index would be used to answer the query.
+func (db *Database) Explain(q FindQuery) (*ExplainResponse, error) {
+	return db.ExplainContext(context.Background(), q)
+}
+
+// ExplainContext is like Explain but includes a context.
+func (db *Database) ExplainContext(ctx context.Context, q FindQuery) (*ExplainResponse, error) {
+	if q.Selector == nil {
+		q.Selector = Selector{}
+	}
+	u := fmt.Sprintf("%s/_explain", url.PathEscape(db.Name))
+	var b bytes.Buffer
+	if err := json.NewEncoder(&b).Encode(q); err != nil {
+		return nil, err
+	}
+	res, err := db.Client.RequestContext(idempotent(ctx), http.MethodPost, u, &b, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	response := &ExplainResponse{}
+	return response, json.NewDecoder(res.Body).Decode(response)
+}
@@ -0,0 +1,246 @@
+package couchdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/google/go-querystring/query"
+)
+
+// GetOptions are the query parameters of a document request.
+// http://docs.couchdb.org/en/latest/api/document/common.html#get--db-docid
+type GetOptions struct {
+	// Rev requests a specific revision instead of the winning one.
+	Rev string `url:"rev,omitempty"`
+	// Revs includes the revision history in DocumentMeta.Revisions.
+	Revs bool `url:"revs,omitempty"`
+	// RevsInfo includes the available revisions in DocumentMeta.RevsInfo.
+	RevsInfo bool `url:"revs_info,omitempty"`
+	// Conflicts includes the conflicting revisions in DocumentMeta.Conflicts.
+	Conflicts bool `url:"conflicts,omitempty"`
+	// DeletedConflicts includes the deleted conflicting revisions in DocumentMeta.DeletedConflicts.
+	DeletedConflicts bool `url:"deleted_conflicts,omitempty"`
+	// Latest returns the latest leaf revision instead of Rev if it was updated.
+	Latest bool `url:"latest,omitempty"`
+	// LocalSeq includes the update sequence of the document in DocumentMeta.LocalSeq.
+	LocalSeq bool `url:"local_seq,omitempty"`
+	// Meta is the same as Conflicts, DeletedConflicts and RevsInfo together.
+	Meta bool `url:"meta,omitempty"`
+}
+
+// DocumentMeta holds the metadata of a document requested with GetOptions.
+type DocumentMeta struct {
+	ID               string     `json:"_id"`
+	Rev              string     `json:"_rev"`
+	Deleted          bool       `json:"_deleted,omitempty"`
+	Revisions        *Revisions `json:"_revisions,omitempty"`
+	RevsInfo         []RevInfo  `json:"_revs_info,omitempty"`
+	Conflicts        []string   `json:"_conflicts,omitempty"`
+	DeletedConflicts []string   `json:"_deleted_conflicts,omitempty"`
+	LocalSeq         Seq        `json:"_local_seq,omitempty"`
+}
+
+// Revisions is the revision history of a document, newest first.
+type Revisions struct {
+	Start int      `json:"start"`
+	IDs   []string `json:"ids"`
+}
+
+// Revs returns the full revisions of the history, e.g. ["3-c", "2-b", "1-a"].
+func (r Revisions) Revs() []string {
+	revs := make([]string, len(r.IDs))
+	for i, id := range r.IDs {
+		revs[i] = fmt.Sprintf("%d-%s", r.Start-i, id)
+	}
+	return revs
+}
+
+// RevInfo describes whether a revision of the document is still available.
+// Status is "available", "missing" or "deleted".
+type RevInfo struct {
+	Rev    string `json:"rev"`
+	Status string `json:"status"`
+}
+
+// GetWithOptions gets the document and the metadata requested with opts.
+//
+//	meta, err := db.GetWithOptions(doc, "john", couchdb.GetOptions{Conflicts: true})
+//	if len(meta.Conflicts) > 0 {
+//		// resolve conflicts
+//	}
+func (db *Database) GetWithOptions(doc CouchDoc, id string, opts GetOptions) (*DocumentMeta, error) {
+	return db.GetWithOptionsContext(context.Background(), doc, id, opts)
+}
+
+// GetWithOptionsContext is like GetWithOptions but includes a context.
+func (db *Database) GetWithOptionsContext(ctx context.Context, doc CouchDoc, id string, opts GetOptions) (*DocumentMeta, error) {
+	q, err := query.Values(opts)
+	if err != nil {
+		return nil, err
+	}
+	u := fmt.Sprintf("%s/%s?%s", url.PathEscape(db.Name), url.PathEscape(id), q.Encode())
+	res, err := db.Client.RequestContext(ctx, http.MethodGet, u, nil, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(body, decodeTarget(doc)); err != nil {
+		return nil, err
+	}
+	meta := &DocumentMeta{}
+	return meta, json.Unmarshal(body, meta)
+}
+
+// OpenRev is a leaf revision returned by GetOpenRevs.
+// Error is set when the requested revision is missing.
+type OpenRev struct {
+	Rev   string
+	Doc   json.RawMessage
+	Meta  DocumentMeta
+	Error *Error
+}
+
+// Decode decodes the revision into doc or returns the error of the missing revision.
+func (r OpenRev) Decode(doc CouchDoc) error {
+	if r.Error != nil {
+		return r.Error
+	}
+	return json.Unmarshal(r.Doc, decodeTarget(doc))
+}
+
+// GetOpenRevs gets the given leaf revisions of a document or all of them when revs is empty.
+// Rev of opts is ignored. Use it to load all sides of a conflict.
+func (db *Database) GetOpenRevs(id string, revs []string, opts GetOptions) ([]OpenRev, error) {
+	return db.GetOpenRevsContext(context.Background(), id, revs, opts)
+}
+
+// GetOpenRevsContext is like GetOpenRevs but includes a context.
+func (db *Database) GetOpenRevsContext(ctx context.Context, id string, revs []string, opts GetOptions) ([]OpenRev, error) {
+	opts.Rev = ""
+	q, err := query.Values(opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(revs) == 0 {
+		q.Set("open_revs", "all")
+	} else {
+		b, err := json.Marshal(revs)
+		if err != nil {
+			return nil, err
+		}
+		q.Set("open_revs", string(b))
+	}
+	u := fmt.Sprintf("%s/%s?%s", url.PathEscape(db.Name), url.PathEscape(id), q.Encode())
+	header := http.Header{}
+	header.Set("Accept", "multipart/mixed, application/json")
+	res, err := db.Client.request(ctx, http.MethodGet, u, nil, header)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	mediaType, params, err := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+	var bodies []json.RawMessage
+	if mediaType == "multipart/mixed" {
+		if bodies, err = readMixed(res.Body, params["boundary"]); err != nil {
+			return nil, err
+		}
+	} else {
+		// the JSON response wraps every revision in {"ok": doc}
+		var results []map[string]json.RawMessage
+		if err := json.NewDecoder(res.Body).Decode(&results); err != nil {
+			return nil, err
+		}
+		for _, result := range results {
+			if doc, ok := result["ok"]; ok {
+				bodies = append(bodies, doc)
+				continue
+			}
+			b, err := json.Marshal(result)
+			if err != nil {
+				return nil, err
+			}
+			bodies = append(bodies, b)
+		}
+	}
+	openRevs := make([]OpenRev, 0, len(bodies))
+	for _, body := range bodies {
+		var missing struct {
+			Missing string `json:"missing"`
+		}
+		if err := json.Unmarshal(body, &missing); err != nil {
+			return nil, err
+		}
+		if missing.Missing != "" {
+			openRevs = append(openRevs, OpenRev{
+				Rev:   missing.Missing,
+				Error: docError(http.MethodGet, u, "not_found", "missing"),
+			})
+			continue
+		}
+		r := OpenRev{Doc: body}
+		if err := json.Unmarshal(body, &r.Meta); err != nil {
+			return nil, err
+		}
+		r.Rev = r.Meta.Rev
+		openRevs = append(openRevs, r)
+	}
+	return openRevs, nil
+}
+
+// readMixed returns the documents of a multipart/mixed response.
+// Documents with attachments are nested multipart/related parts starting with the document.
+func readMixed(r io.Reader, boundary string) ([]json.RawMessage, error) {
+	reader := multipart.NewReader(r, boundary)
+	var bodies []json.RawMessage
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return bodies, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		mediaType, params, err := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(mediaType, "multipart/") {
+			related := multipart.NewReader(part, params["boundary"])
+			first, err := related.NextPart()
+			if err != nil {
+				return nil, err
+			}
+			body, err := ioutil.ReadAll(first)
+			if err != nil {
+				return nil, err
+			}
+			// skip the attachments
+			if _, err := io.Copy(ioutil.Discard, part); err != nil {
+				return nil, err
+			}
+			bodies = append(bodies, bytes.TrimSpace(body))
+			continue
+		}
+		body, err := ioutil.ReadAll(part)
+		if err != nil {
+			return nil, err
+		}
+		bodies = append(bodies, bytes.TrimSpace(body))
+	}
+}
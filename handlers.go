@@ -0,0 +1,119 @@
+package couchdb
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// HandlerResponse is the raw response of a show, list or update function.
+// Body must be closed after reading.
+type HandlerResponse struct {
+	StatusCode  int
+	ContentType string
+	Header      http.Header
+	Body        io.ReadCloser
+	// NewRev is the revision written by an update function or empty if nothing was stored.
+	NewRev string
+}
+
+func newHandlerResponse(res *http.Response) *HandlerResponse {
+	return &HandlerResponse{
+		StatusCode:  res.StatusCode,
+		ContentType: res.Header.Get("Content-Type"),
+		Header:      res.Header,
+		Body:        res.Body,
+		NewRev:      res.Header.Get("X-Couch-Update-NewRev"),
+	}
+}
+
+// Show executes the show function with the document of docID.
+// The function is called without document when docID is empty.
+// query is passed to the function as req.query.
+func (v *View) Show(name, docID string, query url.Values) (*HandlerResponse, error) {
+	return v.ShowContext(context.Background(), name, docID, query)
+}
+
+// ShowContext is like Show but includes a context.
+func (v *View) ShowContext(ctx context.Context, name, docID string, query url.Values) (*HandlerResponse, error) {
+	uri := fmt.Sprintf("%s_show/%s", v.URL, name)
+	if docID != "" {
+		uri += "/" + url.PathEscape(docID)
+	}
+	res, err := v.Client.RequestContext(ctx, http.MethodGet, withQuery(uri, query), nil, "")
+	if err != nil {
+		return nil, err
+	}
+	return newHandlerResponse(res), nil
+}
+
+// List executes the list function with the rows of a view, e.g. to export them as CSV.
+// view can be the name of a view of another design document as "ddoc/view".
+// query holds additional parameters for the function, e.g. the format of the export.
+//
+//	res, err := view.List("csv", "byName", couchdb.QueryParameters{}, url.Values{"sep": {";"}})
+//	if err != nil {
+//		return err
+//	}
+//	defer res.Body.Close()
+//	io.Copy(w, res.Body)
+func (v *View) List(name, view string, params QueryParameters, query url.Values) (*HandlerResponse, error) {
+	return v.ListContext(context.Background(), name, view, params, query)
+}
+
+// ListContext is like List but includes a context.
+func (v *View) ListContext(ctx context.Context, name, view string, params QueryParameters, query url.Values) (*HandlerResponse, error) {
+	q, err := params.values()
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range query {
+		q[key] = values
+	}
+	uri := fmt.Sprintf("%s_list/%s/%s", v.URL, name, view)
+	res, err := v.Client.RequestContext(ctx, http.MethodGet, withQuery(uri, q), nil, "")
+	if err != nil {
+		return nil, err
+	}
+	return newHandlerResponse(res), nil
+}
+
+// Update executes the update function with the document of docID using PUT.
+// Without docID the function is called with POST and without document,
+// e.g. to create a new one. body and query are passed to the function as req.body and req.query.
+//
+//	res, err := view.Update("increment", "counter", url.Values{"by": {"2"}}, nil, "")
+//	if err != nil {
+//		return err
+//	}
+//	defer res.Body.Close()
+//	fmt.Println(res.NewRev)
+func (v *View) Update(name, docID string, query url.Values, body io.Reader, contentType string) (*HandlerResponse, error) {
+	return v.UpdateContext(context.Background(), name, docID, query, body, contentType)
+}
+
+// UpdateContext is like Update but includes a context.
+func (v *View) UpdateContext(ctx context.Context, name, docID string, query url.Values, body io.Reader, contentType string) (*HandlerResponse, error) {
+	// update functions can change documents on every call so they are never retried
+	method := http.MethodPost
+	uri := fmt.Sprintf("%s_update/%s", v.URL, name)
+	if docID != "" {
+		method = http.MethodPut
+		uri += "/" + url.PathEscape(docID)
+	}
+	res, err := v.Client.RequestContext(ctx, method, withQuery(uri, query), body, contentType)
+	if err != nil {
+		return nil, err
+	}
+	return newHandlerResponse(res), nil
+}
+
+// withQuery appends the encoded query to uri.
+func withQuery(uri string, query url.Values) string {
+	if len(query) == 0 {
+		return uri
+	}
+	return uri + "?" + query.Encode()
+}
@@ -0,0 +1,319 @@
+package couchdb
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"sort"
+)
+
+// AttachmentUpload is a new attachment stored together with its document by PutWithAttachments.
+type AttachmentUpload struct {
+	Name        string
+	ContentType string
+	// Length is the size of the content. It may be zero when Body is an *os.File
+	// or has a Len method like *bytes.Reader and *strings.Reader.
+	Length int64
+	Body   io.Reader
+}
+
+// size returns the length of the content or an error if it is unknown.
+func (a AttachmentUpload) size() (int64, error) {
+	if a.Length > 0 {
+		return a.Length, nil
+	}
+	switch body := a.Body.(type) {
+	case interface{ Len() int }:
+		return int64(body.Len()), nil
+	case *os.File:
+		stat, err := body.Stat()
+		if err != nil {
+			return 0, err
+		}
+		return stat.Size(), nil
+	}
+	return 0, fmt.Errorf("couchdb: unknown length of attachment %s", a.Name)
+}
+
+// sizedReader is a body with a known length that is not sent chunked.
+type sizedReader struct {
+	io.Reader
+	size int64
+}
+
+// PutWithAttachments creates or updates the document together with new attachments
+// in a single multipart/related request. The content is streamed without holding it in memory.
+// Attachments the document already has are kept as long as their stubs are part of the document.
+//
+//	res, err := db.PutWithAttachments(doc,
+//		couchdb.AttachmentUpload{Name: "cover.jpg", ContentType: "image/jpeg", Body: cover},
+//		couchdb.AttachmentUpload{Name: "back.jpg", ContentType: "image/jpeg", Body: back},
+//	)
+func (db *Database) PutWithAttachments(doc CouchDoc, attachments ...AttachmentUpload) (*DocumentResponse, error) {
+	return db.PutWithAttachmentsContext(context.Background(), doc, attachments...)
+}
+
+// PutWithAttachmentsContext is like PutWithAttachments but includes a context.
+func (db *Database) PutWithAttachmentsContext(ctx context.Context, doc CouchDoc, attachments ...AttachmentUpload) (*DocumentResponse, error) {
+	// CouchDB matches the parts to the attachments in the order of the JSON object
+	// which is sorted by name when encoded from a map
+	uploads := map[string]AttachmentUpload{}
+	for _, a := range attachments {
+		uploads[a.Name] = a
+	}
+	names := make([]string, 0, len(uploads))
+	for name := range uploads {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	body, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, err
+	}
+	stubs := map[string]json.RawMessage{}
+	if raw, ok := fields["_attachments"]; ok && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &stubs); err != nil {
+			return nil, err
+		}
+	}
+	var length int64
+	for _, name := range names {
+		size, err := uploads[name].size()
+		if err != nil {
+			return nil, err
+		}
+		length += size
+		if stubs[name], err = json.Marshal(Attachment{
+			Follows:     true,
+			ContentType: uploads[name].ContentType,
+			Length:      size,
+		}); err != nil {
+			return nil, err
+		}
+	}
+	if fields["_attachments"], err = json.Marshal(stubs); err != nil {
+		return nil, err
+	}
+	if body, err = json.Marshal(fields); err != nil {
+		return nil, err
+	}
+
+	// measure the multipart envelope so the request has a Content-Length
+	counter := &countingWriter{}
+	envelope := multipart.NewWriter(counter)
+	if err := writeParts(envelope, body, names, nil); err != nil {
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+	defer pr.Close()
+	writer := multipart.NewWriter(pw)
+	if err := writer.SetBoundary(envelope.Boundary()); err != nil {
+		return nil, err
+	}
+	go func() {
+		pw.CloseWithError(writeParts(writer, body, names, uploads))
+	}()
+
+	u := fmt.Sprintf("%s/%s", url.PathEscape(db.Name), url.PathEscape(doc.GetID()))
+	header := http.Header{}
+	header.Set("Content-Type", fmt.Sprintf("multipart/related; boundary=%q", writer.Boundary()))
+	res, err := db.Client.request(ctx, http.MethodPut, u, &sizedReader{pr, counter.n + length}, header)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	var response DocumentResponse
+	return &response, json.NewDecoder(res.Body).Decode(&response)
+}
+
+// writeParts writes the document followed by one part per attachment.
+// Only the envelope is written when uploads is nil.
+func writeParts(writer *multipart.Writer, body []byte, names []string, uploads map[string]AttachmentUpload) error {
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", "application/json")
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	if _, err := part.Write(body); err != nil {
+		return err
+	}
+	for _, name := range names {
+		part, err := writer.CreatePart(textproto.MIMEHeader{})
+		if err != nil {
+			return err
+		}
+		if uploads == nil {
+			continue
+		}
+		if _, err := io.Copy(part, uploads[name].Body); err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+// countingWriter counts the written bytes.
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// attachmentsDoc is implemented by documents embedding Document.
+type attachmentsDoc interface {
+	attachments() map[string]Attachment
+}
+
+// GetWithAttachments gets the document together with the content of all its attachments.
+// The content is stored in Attachment.Content of the Attachments of the embedded Document
+// or as Attachment values in the _attachments map of an ArbitraryDoc.
+// The attachments are turned into stubs, so the document can be updated with Put without losing them.
+// The content is verified against the digest of every attachment CouchDB does not store compressed.
+func (db *Database) GetWithAttachments(doc CouchDoc, id string) error {
+	return db.GetWithAttachmentsContext(context.Background(), doc, id)
+}
+
+// GetWithAttachmentsContext is like GetWithAttachments but includes a context.
+func (db *Database) GetWithAttachmentsContext(ctx context.Context, doc CouchDoc, id string) error {
+	u := fmt.Sprintf("%s/%s?attachments=true&att_encoding_info=true", url.PathEscape(db.Name), url.PathEscape(id))
+	header := http.Header{}
+	header.Set("Accept", "multipart/related, application/json")
+	res, err := db.Client.request(ctx, http.MethodGet, u, nil, header)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	mediaType, params, err := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	if err != nil {
+		return err
+	}
+	// documents without attachments are sent as plain JSON
+	if mediaType != "multipart/related" {
+		return json.NewDecoder(res.Body).Decode(decodeTarget(doc))
+	}
+	reader := multipart.NewReader(res.Body, params["boundary"])
+	part, err := reader.NextPart()
+	if err != nil {
+		return err
+	}
+	body, err := ioutil.ReadAll(part)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, decodeTarget(doc)); err != nil {
+		return err
+	}
+	names, attachments, err := followingAttachments(body)
+	if err != nil {
+		return err
+	}
+	for i := 0; ; i++ {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		// CouchDB 1.6 sends the parts without name in the order of the JSON object
+		name := partName(part)
+		if name == "" && i < len(names) {
+			name = names[i]
+		}
+		a := attachments[name]
+		want := decodeDigest(a.Digest)
+		if a.Encoding != "" {
+			// the digest is computed over the compressed content but the part holds the decompressed one
+			want = nil
+		}
+		content, err := ioutil.ReadAll(&verifyingReader{
+			Reader: part,
+			hash:   md5.New(),
+			want:   want,
+		})
+		if err != nil {
+			return fmt.Errorf("couchdb: reading attachment %s: %w", name, err)
+		}
+		a.Content = content
+		a.Follows = false
+		a.Stub = true
+		attachments[name] = a
+	}
+	switch d := doc.(type) {
+	case ArbitraryDoc:
+		d["_attachments"] = attachments
+	case attachmentsDoc:
+		if m := d.attachments(); m != nil {
+			for name, a := range attachments {
+				m[name] = a
+			}
+		}
+	}
+	return nil
+}
+
+// followingAttachments returns the attachments of an encoded document
+// and the names of those sent as separate parts in the order of the JSON object.
+func followingAttachments(body []byte) ([]string, map[string]Attachment, error) {
+	var doc struct {
+		Attachments json.RawMessage `json:"_attachments"`
+	}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, nil, err
+	}
+	attachments := map[string]Attachment{}
+	if len(doc.Attachments) == 0 {
+		return nil, attachments, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(doc.Attachments))
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, nil, err
+	}
+	var names []string
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		name, _ := t.(string)
+		var a Attachment
+		if err := dec.Decode(&a); err != nil {
+			return nil, nil, err
+		}
+		if a.Follows {
+			names = append(names, name)
+		}
+		attachments[name] = a
+	}
+	return names, attachments, nil
+}
+
+// partName returns the unmodified file name of a part.
+// multipart.Part.FileName would cut names containing slashes.
+func partName(part *multipart.Part) string {
+	_, params, err := mime.ParseMediaType(part.Header.Get("Content-Disposition"))
+	if err != nil {
+		return ""
+	}
+	return params["filename"]
+}
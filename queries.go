@@ -0,0 +1,125 @@
+package couchdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// Queries executes several queries of the view with a single request.
+// The responses are in the order of queries.
+//
+//	res, err := view.Queries("byName", []couchdb.QueryParameters{
+//		{Key: "john"},
+//		{StartKey: "a", EndKey: "b", Limit: &limit},
+//	})
+//
+// CouchDB before 2.2 has no queries endpoint. The queries are sent as individual requests in parallel instead.
+func (v *View) Queries(name string, queries []QueryParameters) ([]ViewResponse, error) {
+	return v.QueriesContext(context.Background(), name, queries)
+}
+
+// QueriesContext is like Queries but includes a context.
+func (v *View) QueriesContext(ctx context.Context, name string, queries []QueryParameters) ([]ViewResponse, error) {
+	uri := fmt.Sprintf("%s_view/%s/queries", v.URL, name)
+	return multiQuery(ctx, v.Client, uri, queries, func(ctx context.Context, params QueryParameters) (*ViewResponse, error) {
+		if !isNil(params.Keys) {
+			return v.PostContext(ctx, name, nil, params)
+		}
+		return v.GetContext(ctx, name, params)
+	})
+}
+
+// AllDocsQueries executes several queries of _all_docs with a single request.
+// See View.Queries for details.
+func (db *Database) AllDocsQueries(queries []QueryParameters) ([]ViewResponse, error) {
+	return db.AllDocsQueriesContext(context.Background(), queries)
+}
+
+// AllDocsQueriesContext is like AllDocsQueries but includes a context.
+func (db *Database) AllDocsQueriesContext(ctx context.Context, queries []QueryParameters) ([]ViewResponse, error) {
+	uri := fmt.Sprintf("%s/_all_docs/queries", url.PathEscape(db.Name))
+	return multiQuery(ctx, db.Client, uri, queries, func(ctx context.Context, params QueryParameters) (*ViewResponse, error) {
+		return db.AllDocsContext(ctx, &params)
+	})
+}
+
+// multiQuery posts the queries to uri and falls back to single requests
+// when the server does not know the endpoint.
+func multiQuery(ctx context.Context, c *Client, uri string, queries []QueryParameters, single func(context.Context, QueryParameters) (*ViewResponse, error)) ([]ViewResponse, error) {
+	if len(queries) == 0 {
+		return []ViewResponse{}, nil
+	}
+	results, err := postQueries(ctx, c, uri, queries)
+	if missingEndpoint(err) {
+		return queryEach(ctx, queries, single)
+	}
+	return results, err
+}
+
+func postQueries(ctx context.Context, c *Client, uri string, queries []QueryParameters) ([]ViewResponse, error) {
+	content := struct {
+		Queries []QueryParameters `json:"queries"`
+	}{
+		Queries: queries,
+	}
+	var b bytes.Buffer
+	if err := json.NewEncoder(&b).Encode(content); err != nil {
+		return nil, err
+	}
+	res, err := c.RequestContext(idempotent(ctx), http.MethodPost, uri, &b, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	var response struct {
+		Results []ViewResponse `json:"results"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return nil, err
+	}
+	if len(response.Results) != len(queries) {
+		return nil, fmt.Errorf("couchdb: expected %d results but got %d", len(queries), len(response.Results))
+	}
+	return response.Results, nil
+}
+
+// queryEach executes the queries in parallel and cancels the remaining ones after the first error.
+func queryEach(ctx context.Context, queries []QueryParameters, single func(context.Context, QueryParameters) (*ViewResponse, error)) ([]ViewResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make([]ViewResponse, len(queries))
+	errs := make([]error, len(queries))
+	var wg sync.WaitGroup
+	for i, params := range queries {
+		wg.Add(1)
+		go func(i int, params QueryParameters) {
+			defer wg.Done()
+			res, err := single(ctx, params)
+			if err != nil {
+				errs[i] = err
+				cancel()
+				return
+			}
+			results[i] = *res
+		}(i, params)
+	}
+	wg.Wait()
+	// report the cause and not the cancellation of the other queries
+	for _, err := range errs {
+		if err != nil && !errors.Is(err, context.Canceled) {
+			return nil, err
+		}
+	}
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
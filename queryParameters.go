@@ -1,24 +1,86 @@
 package couchdb
 
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/google/go-querystring/query"
+)
+
 // QueryParameters is struct to define url query parameters for design documents.
 // http://docs.couchdb.org/en/latest/api/ddoc/views.html#db-design-design-doc-view-view-name
+//
+// Key, Keys, StartKey and EndKey take any value that can be encoded to JSON,
+// e.g. "john" or []interface{}{"user", 42, map[string]interface{}{}}.
+// They are encoded by the client, so a *string is rejected as it is most likely already encoded.
+// StartKey and EndKey are sent as startkey and endkey. CouchDB treats start_key and end_key
+// as aliases of the same parameters, so there are no separate fields for them.
+//
+//	params := couchdb.QueryParameters{
+//		StartKey: []interface{}{"user", 42},
+//		EndKey:   []interface{}{"user", 42, map[string]interface{}{}},
+//	}
 type QueryParameters struct {
-	Conflicts       *bool   `url:"conflicts,omitempty"`
-	Descending      *bool   `url:"descending,omitempty"`
-	Group           *bool   `url:"group,omitempty"`
-	IncludeDocs     *bool   `url:"include_docs,omitempty"`
-	Attachments     *bool   `url:"attachments,omitempty"`
-	AttEncodingInfo *bool   `url:"att_encoding_info,omitempty"`
-	InclusiveEnd    *bool   `url:"inclusive_end,omitempty"`
-	Reduce          *bool   `url:"reduce,omitempty"`
-	UpdateSeq       *bool   `url:"update_seq,omitempty"`
-	GroupLevel      *int    `url:"group_level,omitempty"`
-	Limit           *int    `url:"limit,omitempty"`
-	Skip            *int    `url:"skip,omitempty"`
-	Key             *string `url:"key,omitempty"`
-	EndKey          *string `url:"endkey,comma,omitempty"`
-	EndKeyDocID     *string `url:"end_key_doc_id,omitempty"`
-	Stale           *string `url:"stale,omitempty"`
-	StartKey        *string `url:"startkey,comma,omitempty"`
-	StartKeyDocID   *string `url:"startkey_docid,omitempty"`
+	Conflicts       *bool   `url:"conflicts,omitempty" json:"conflicts,omitempty"`
+	Descending      *bool   `url:"descending,omitempty" json:"descending,omitempty"`
+	Group           *bool   `url:"group,omitempty" json:"group,omitempty"`
+	IncludeDocs     *bool   `url:"include_docs,omitempty" json:"include_docs,omitempty"`
+	Attachments     *bool   `url:"attachments,omitempty" json:"attachments,omitempty"`
+	AttEncodingInfo *bool   `url:"att_encoding_info,omitempty" json:"att_encoding_info,omitempty"`
+	InclusiveEnd    *bool   `url:"inclusive_end,omitempty" json:"inclusive_end,omitempty"`
+	Reduce          *bool   `url:"reduce,omitempty" json:"reduce,omitempty"`
+	UpdateSeq       *bool   `url:"update_seq,omitempty" json:"update_seq,omitempty"`
+	GroupLevel      *int    `url:"group_level,omitempty" json:"group_level,omitempty"`
+	Limit           *int    `url:"limit,omitempty" json:"limit,omitempty"`
+	Skip            *int    `url:"skip,omitempty" json:"skip,omitempty"`
+	EndKeyDocID     *string `url:"end_key_doc_id,omitempty" json:"end_key_doc_id,omitempty"`
+	StartKeyDocID   *string `url:"startkey_docid,omitempty" json:"start_key_doc_id,omitempty"`
+	// Deprecated: Stale is replaced by Update and Stable since CouchDB 2.1.
+	Stale *string `url:"stale,omitempty" json:"stale,omitempty"`
+	// Update is "true", "false" or "lazy" to update the view before, never or after responding.
+	Update *string `url:"update,omitempty" json:"update,omitempty"`
+	// Stable returns results from a stable set of shards.
+	Stable *bool `url:"stable,omitempty" json:"stable,omitempty"`
+
+	Key interface{} `url:"-" json:"key,omitempty"`
+	// Keys must be encoded to a JSON array.
+	Keys     interface{} `url:"-" json:"keys,omitempty"`
+	StartKey interface{} `url:"-" json:"start_key,omitempty"`
+	EndKey   interface{} `url:"-" json:"end_key,omitempty"`
+}
+
+// values returns the query string with JSON encoded keys.
+func (p *QueryParameters) values() (url.Values, error) {
+	if p == nil {
+		return url.Values{}, nil
+	}
+	q, err := query.Values(p)
+	if err != nil {
+		return nil, err
+	}
+	keys := []struct {
+		name  string
+		value interface{}
+	}{
+		{"key", p.Key},
+		{"keys", p.Keys},
+		{"startkey", p.StartKey},
+		{"endkey", p.EndKey},
+	}
+	for _, key := range keys {
+		if isNil(key.value) {
+			continue
+		}
+		// keys used to be encoded by the caller, encoding them again would silently match nothing
+		if _, ok := key.value.(*string); ok {
+			return nil, fmt.Errorf("couchdb: %s must be a value and not a *string, it is encoded to JSON by the client", key.name)
+		}
+		b, err := json.Marshal(key.value)
+		if err != nil {
+			return nil, err
+		}
+		q.Set(key.name, string(b))
+	}
+	return q, nil
 }
@@ -0,0 +1,133 @@
+package couchdb
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy describes how requests failing with transient errors are retried.
+// Safe methods (GET and HEAD) are always retried. PUT, POST and DELETE requests are only
+// retried when the operation is known to be idempotent, e.g. Put with an explicit revision.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts including the first one.
+	MaxAttempts int
+	// MinBackoff is the delay before the first retry. It doubles with every further attempt.
+	MinBackoff time.Duration
+	// MaxBackoff is the upper limit for the delay between two attempts.
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryPolicy is a reasonable policy for CouchDB clusters behind a load balancer.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 4,
+	MinBackoff:  100 * time.Millisecond,
+	MaxBackoff:  5 * time.Second,
+}
+
+// WithRetry enables automatic retries of failed requests with the given policy.
+func WithRetry(p RetryPolicy) Option {
+	return func(c *Client) {
+		c.Retry = &p
+	}
+}
+
+// backoff returns the time to wait before the given attempt.
+// A Retry-After header sent by the server takes precedence over the exponential backoff.
+func (p *RetryPolicy) backoff(attempt int, res *http.Response) time.Duration {
+	if res != nil {
+		if d, ok := retryAfter(res.Header.Get("Retry-After")); ok {
+			return d
+		}
+	}
+	d := p.MinBackoff << uint(attempt-1)
+	if d <= 0 || (p.MaxBackoff > 0 && d > p.MaxBackoff) {
+		d = p.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	// equal jitter keeps at least half of the delay and spreads the rest
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(d-half)+1))
+}
+
+// retryAfter parses the Retry-After header which is either a number of seconds or an HTTP date.
+func retryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// retryable reports whether a request that ended with the given response or error should be tried again.
+func retryable(res *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch res.StatusCode {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// isTransient reports whether a failed request may succeed when it is sent again.
+// Errors without a status code are network errors.
+func isTransient(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return retryable(&http.Response{StatusCode: e.StatusCode}, nil)
+	}
+	return true
+}
+
+type idempotentKey struct{}
+
+// idempotent marks requests made with the returned context as safe to repeat.
+// It is used for POST requests that only read, like queries, _find and _bulk_get,
+// since sending them again does not change anything.
+func idempotent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, idempotentKey{}, true)
+}
+
+// isIdempotent reports whether a request may be sent more than once without changing its outcome.
+func isIdempotent(ctx context.Context, method string) bool {
+	if method == http.MethodGet || method == http.MethodHead {
+		return true
+	}
+	marked, _ := ctx.Value(idempotentKey{}).(bool)
+	return marked
+}
+
+// sleep waits for the given duration or until the context is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,168 @@
+package couchdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+)
+
+// DefaultPageSize is the number of rows requested per page when iterating without an explicit page size.
+const DefaultPageSize = 1000
+
+// AllDocsRows iterates over all rows of _all_docs.
+// See View.Rows for details about paging.
+func (db *Database) AllDocsRows(params QueryParameters, pageSize int) iter.Seq2[Row, error] {
+	return db.AllDocsRowsContext(context.Background(), params, pageSize)
+}
+
+// AllDocsRowsContext is like AllDocsRows but includes a context.
+func (db *Database) AllDocsRowsContext(ctx context.Context, params QueryParameters, pageSize int) iter.Seq2[Row, error] {
+	return paginate(ctx, params, pageSize, func(ctx context.Context, params QueryParameters) (*http.Response, error) {
+		return db.requestAllDocs(ctx, &params)
+	})
+}
+
+// Rows iterates over all rows of the view, e.g. to scan very large result sets.
+//
+//	for row, err := range view.Rows("byName", couchdb.QueryParameters{}, 500) {
+//		if err != nil {
+//			return err
+//		}
+//		// use row
+//	}
+//
+// Rows are requested in pages of pageSize rows using startkey and startkey_docid
+// instead of skip, so every page is as fast as the first one.
+// Each page is decoded row by row while it is read from the connection.
+// Limit and Skip of params apply to the whole iteration.
+func (v *View) Rows(name string, params QueryParameters, pageSize int) iter.Seq2[Row, error] {
+	return v.RowsContext(context.Background(), name, params, pageSize)
+}
+
+// RowsContext is like Rows but includes a context.
+func (v *View) RowsContext(ctx context.Context, name string, params QueryParameters, pageSize int) iter.Seq2[Row, error] {
+	return paginate(ctx, params, pageSize, func(ctx context.Context, params QueryParameters) (*http.Response, error) {
+		return v.request(ctx, name, params)
+	})
+}
+
+// pageRow is a row that keeps the raw key to request the next page.
+type pageRow struct {
+	Row
+	RawKey json.RawMessage `json:"key"`
+}
+
+// paginate requests one more row than pageSize per page.
+// The additional row is the first row of the next page.
+func paginate(ctx context.Context, params QueryParameters, pageSize int, fetch func(context.Context, QueryParameters) (*http.Response, error)) iter.Seq2[Row, error] {
+	return func(yield func(Row, error) bool) {
+		if pageSize <= 0 {
+			pageSize = DefaultPageSize
+		}
+		if !isNil(params.Keys) {
+			yield(Row{}, errors.New("couchdb: rows of explicit keys cannot be paginated"))
+			return
+		}
+		remaining := -1
+		if params.Limit != nil {
+			remaining = *params.Limit
+		}
+		// a single key would override startkey for every page
+		if !isNil(params.Key) {
+			params.StartKey = params.Key
+			params.EndKey = params.Key
+			params.Key = nil
+		}
+		limit := pageSize + 1
+		params.Limit = &limit
+		for remaining != 0 {
+			res, err := fetch(ctx, params)
+			if err != nil {
+				yield(Row{}, err)
+				return
+			}
+			next, more, err := readPage(res.Body, pageSize, func(row Row) bool {
+				if remaining == 0 {
+					return false
+				}
+				remaining--
+				return yield(row, nil)
+			})
+			res.Body.Close()
+			if err != nil {
+				yield(Row{}, err)
+				return
+			}
+			if !more {
+				return
+			}
+			params.StartKey = next.RawKey
+			params.StartKeyDocID = nil
+			if next.ID != "" {
+				startKeyDocID := next.ID
+				params.StartKeyDocID = &startKeyDocID
+			}
+			params.Skip = nil
+		}
+	}
+}
+
+// readPage decodes the rows of a view response one after another and passes
+// the first pageSize rows to fn. It returns the following row if there is one.
+// more is false when fn stopped the iteration or no further rows exist.
+func readPage(r io.Reader, pageSize int, fn func(Row) bool) (next pageRow, more bool, err error) {
+	dec := json.NewDecoder(r)
+	if err := expectDelim(dec, '{'); err != nil {
+		return next, false, err
+	}
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return next, false, err
+		}
+		if key, _ := t.(string); key != "rows" {
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return next, false, err
+			}
+			continue
+		}
+		if err := expectDelim(dec, '['); err != nil {
+			return next, false, err
+		}
+		for i := 0; dec.More(); i++ {
+			var row pageRow
+			if err := dec.Decode(&row); err != nil {
+				return next, false, err
+			}
+			if i == pageSize {
+				return row, true, nil
+			}
+			if len(row.RawKey) > 0 {
+				if err := json.Unmarshal(row.RawKey, &row.Key); err != nil {
+					return next, false, err
+				}
+			}
+			if !fn(row.Row) {
+				return next, false, nil
+			}
+		}
+		return next, false, nil
+	}
+	return next, false, nil
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := t.(json.Delim); !ok || d != delim {
+		return fmt.Errorf("couchdb: expected %s in response but got %v", delim, t)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package couchdb
+
+// Selector is a Mango selector used to find documents.
+// Selectors are built with the functions below and can be combined with And, Or, Nor and Not.
+//
+//	sel := couchdb.And(
+//		couchdb.Eq("type", "player"),
+//		couchdb.Gte("age", 18),
+//		couchdb.In("team", "red", "blue"),
+//	)
+//
+// http://docs.couchdb.org/en/latest/api/database/find.html#selector-syntax
+type Selector map[string]interface{}
+
+// field creates a selector applying a single condition operator to a field.
+func field(name, operator string, value interface{}) Selector {
+	return Selector{
+		name: map[string]interface{}{
+			operator: value,
+		},
+	}
+}
+
+// Eq matches documents whose field is equal to value.
+func Eq(name string, value interface{}) Selector {
+	return field(name, "$eq", value)
+}
+
+// Ne matches documents whose field is not equal to value.
+func Ne(name string, value interface{}) Selector {
+	return field(name, "$ne", value)
+}
+
+// Gt matches documents whose field is greater than value.
+func Gt(name string, value interface{}) Selector {
+	return field(name, "$gt", value)
+}
+
+// Gte matches documents whose field is greater than or equal to value.
+func Gte(name string, value interface{}) Selector {
+	return field(name, "$gte", value)
+}
+
+// Lt matches documents whose field is less than value.
+func Lt(name string, value interface{}) Selector {
+	return field(name, "$lt", value)
+}
+
+// Lte matches documents whose field is less than or equal to value.
+func Lte(name string, value interface{}) Selector {
+	return field(name, "$lte", value)
+}
+
+// In matches documents whose field is equal to one of the values.
+func In(name string, values ...interface{}) Selector {
+	return field(name, "$in", values)
+}
+
+// Nin matches documents whose field is equal to none of the values.
+func Nin(name string, values ...interface{}) Selector {
+	return field(name, "$nin", values)
+}
+
+// Exists matches documents that have (or do not have) the field.
+func Exists(name string, exists bool) Selector {
+	return field(name, "$exists", exists)
+}
+
+// Type matches documents whose field is of the given JSON type,
+// i.e. "null", "boolean", "number", "string", "array" or "object".
+func Type(name, typ string) Selector {
+	return field(name, "$type", typ)
+}
+
+// Size matches documents whose array field has exactly the given length.
+func Size(name string, length int) Selector {
+	return field(name, "$size", length)
+}
+
+// Mod matches documents whose field modulo divisor is equal to remainder.
+func Mod(name string, divisor, remainder int) Selector {
+	return field(name, "$mod", []int{divisor, remainder})
+}
+
+// Regex matches documents whose string field matches the Erlang regular expression.
+func Regex(name, pattern string) Selector {
+	return field(name, "$regex", pattern)
+}
+
+// All matches documents whose array field contains all of the values.
+func All(name string, values ...interface{}) Selector {
+	return field(name, "$all", values)
+}
+
+// ElemMatch matches documents whose array field contains at least one element matching sel.
+func ElemMatch(name string, sel Selector) Selector {
+	return field(name, "$elemMatch", sel)
+}
+
+// AllMatch matches documents whose array field only contains elements matching sel.
+func AllMatch(name string, sel Selector) Selector {
+	return field(name, "$allMatch", sel)
+}
+
+// And matches documents matching all of the selectors.
+func And(selectors ...Selector) Selector {
+	return Selector{"$and": selectors}
+}
+
+// Or matches documents matching at least one of the selectors.
+func Or(selectors ...Selector) Selector {
+	return Selector{"$or": selectors}
+}
+
+// Nor matches documents matching none of the selectors.
+func Nor(selectors ...Selector) Selector {
+	return Selector{"$nor": selectors}
+}
+
+// Not matches documents not matching the selector.
+func Not(sel Selector) Selector {
+	return Selector{"$not": sel}
+}
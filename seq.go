@@ -0,0 +1,64 @@
+package couchdb
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// SeqNow can be used as Since parameter to only get changes made from now on.
+const SeqNow Seq = "now"
+
+// Seq is an update sequence.
+// CouchDB 1.x uses integers whereas clustered CouchDB 2.x and later use opaque strings.
+// Seq keeps either form so it can be persisted and passed back as since parameter.
+type Seq string
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+//
+// https://golang.org/pkg/encoding/json/#Unmarshaler
+func (s *Seq) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	switch {
+	case bytes.Equal(data, []byte("null")):
+		*s = ""
+	case len(data) > 0 && data[0] == '"':
+		var tmp string
+		if err := json.Unmarshal(data, &tmp); err != nil {
+			return err
+		}
+		*s = Seq(tmp)
+	default:
+		// numbers from CouchDB 1.x and arrays from BigCouch are kept as raw JSON
+		*s = Seq(data)
+	}
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+// Sequences are written back in the form CouchDB sent them.
+//
+// https://golang.org/pkg/encoding/json/#Marshaler
+func (s Seq) MarshalJSON() ([]byte, error) {
+	if s.IsNumber() || (len(s) > 0 && s[0] == '[') {
+		return []byte(s), nil
+	}
+	return json.Marshal(string(s))
+}
+
+// IsNumber reports whether the sequence is a CouchDB 1.x integer sequence.
+func (s Seq) IsNumber() bool {
+	if len(s) == 0 {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the sequence as it is sent in the since query parameter.
+func (s Seq) String() string {
+	return string(s)
+}
@@ -0,0 +1,67 @@
+package couchdb
+
+import (
+	"context"
+	"errors"
+)
+
+// DefaultConflictRetries is the number of conflict retries of a new client.
+const DefaultConflictRetries = 10
+
+// Update applies changes to the latest revision of a document.
+// It fetches the document with the given id into doc, calls fn to modify it and saves the result.
+// When somebody else updated the document in the meantime, the document is
+// fetched again and fn is called again up to Client.ConflictRetries times.
+// fn must therefore only change doc and not have other side effects.
+// The update request itself is not retried by Client.Retry, since a retry after a lost
+// response would fail with a conflict and apply fn to the already updated document again.
+//
+//	doc := &Counter{}
+//	_, err := db.Update("visits", doc, func(couchdb.CouchDoc) error {
+//		doc.Count++
+//		return nil
+//	})
+//
+// An ArbitraryDoc must be a non-nil map.
+func (db *Database) Update(id string, doc CouchDoc, fn func(CouchDoc) error) (*DocumentResponse, error) {
+	return db.UpdateContext(context.Background(), id, doc, fn)
+}
+
+// UpdateContext is like Update but includes a context.
+func (db *Database) UpdateContext(ctx context.Context, id string, doc CouchDoc, fn func(CouchDoc) error) (*DocumentResponse, error) {
+	return db.update(ctx, id, doc, fn, false)
+}
+
+// Upsert is like Update but creates the document when it does not exist.
+// In that case fn is called with an empty doc.
+func (db *Database) Upsert(id string, doc CouchDoc, fn func(CouchDoc) error) (*DocumentResponse, error) {
+	return db.UpsertContext(context.Background(), id, doc, fn)
+}
+
+// UpsertContext is like Upsert but includes a context.
+func (db *Database) UpsertContext(ctx context.Context, id string, doc CouchDoc, fn func(CouchDoc) error) (*DocumentResponse, error) {
+	return db.update(ctx, id, doc, fn, true)
+}
+
+func (db *Database) update(ctx context.Context, id string, doc CouchDoc, fn func(CouchDoc) error, create bool) (*DocumentResponse, error) {
+	for attempt := 0; ; attempt++ {
+		resetDoc(doc)
+		if err := db.get(ctx, decodeTarget(doc), id); err != nil {
+			if !create || !errors.Is(err, ErrNotFound) {
+				return nil, err
+			}
+			if a, ok := doc.(ArbitraryDoc); ok {
+				a["_id"] = id
+			}
+		}
+		if err := fn(doc); err != nil {
+			return nil, err
+		}
+		// a retried PUT whose first response was lost fails with a conflict
+		// and would apply fn a second time, so it is sent only once
+		res, err := db.putOnce(ctx, id, doc)
+		if err == nil || !errors.Is(err, ErrConflict) || attempt >= db.Client.ConflictRetries {
+			return res, err
+		}
+	}
+}
@@ -3,13 +3,9 @@ package couchdb
 import (
 	"bytes"
 	"encoding/json"
-	"io"
 	"io/ioutil"
 	"mime"
-	"mime/multipart"
 	"net/http"
-	"net/textproto"
-	"os"
 	"path/filepath"
 
 	"github.com/zemirco/uid"
@@ -22,76 +18,24 @@ func mimeType(name string) string {
 }
 
 // Convert HTTP response from CouchDB into Error.
+// Responses without a JSON body, like those to HEAD requests or error pages
+// from a proxy, still result in an Error with status code and raw body.
 func newError(res *http.Response) error {
-	body, err := ioutil.ReadAll(res.Body)
-	if err != nil {
-		return err
-	}
 	defer res.Body.Close()
-	error := &Error{}
-	err = json.Unmarshal(body, &error)
-	if err != nil {
-		return err
-	}
-	error.Method = res.Request.Method
-	error.URL = res.Request.URL.String()
-	error.StatusCode = res.StatusCode
-	return error
-}
-
-// Write JSON to multipart/related.
-func writeJSON(document *Document, writer *multipart.Writer, file *os.File) error {
-	partHeaders := textproto.MIMEHeader{}
-	partHeaders.Set("Content-Type", "application/json")
-	part, err := writer.CreatePart(partHeaders)
-	if err != nil {
-		return err
-	}
-
-	stat, err := file.Stat()
-	if err != nil {
-		return err
-	}
-
-	path := file.Name()
-
-	// make empty map
-	document.Attachments = make(map[string]Attachment)
-	attachment := Attachment{
-		Follows:     true,
-		ContentType: mimeType(path),
-		Length:      stat.Size(),
-	}
-	// add attachment to map
-	filename := filepath.Base(path)
-	document.Attachments[filename] = attachment
-
-	bytes, err := json.Marshal(document)
-	if err != nil {
-		return err
-	}
-
-	_, err = part.Write(bytes)
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
-// Write actual file content to multipart/related.
-func writeMultipart(writer *multipart.Writer, file io.Reader) error {
-	part, err := writer.CreatePart(textproto.MIMEHeader{})
+	body, err := ioutil.ReadAll(res.Body)
 	if err != nil {
 		return err
 	}
-
-	// copy file content into multipart message
-	_, err = io.Copy(part, file)
-	if err != nil {
-		return err
+	var e Error
+	if len(body) > 0 {
+		// ignore invalid JSON and keep the raw body instead
+		_ = json.Unmarshal(body, &e)
 	}
-
-	return nil
+	e.Method = res.Request.Method
+	e.URL = res.Request.URL.String()
+	e.StatusCode = res.StatusCode
+	e.Body = body
+	return &e
 }
 
 // RandDBName returns random CouchDB database name.
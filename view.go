@@ -2,17 +2,35 @@ package couchdb
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"iter"
 	"net/http"
-
-	"github.com/google/go-querystring/query"
+	"net/url"
 )
 
 // ViewService is an interface for dealing with a view inside a CouchDB database.
 type ViewService interface {
 	Get(name string, params QueryParameters) (*ViewResponse, error)
-	Post(name string, keys []string, params QueryParameters) (*ViewResponse, error)
+	GetContext(ctx context.Context, name string, params QueryParameters) (*ViewResponse, error)
+	Post(name string, keys interface{}, params QueryParameters) (*ViewResponse, error)
+	PostContext(ctx context.Context, name string, keys interface{}, params QueryParameters) (*ViewResponse, error)
+	Rows(name string, params QueryParameters, pageSize int) iter.Seq2[Row, error]
+	RowsContext(ctx context.Context, name string, params QueryParameters, pageSize int) iter.Seq2[Row, error]
+	GetRaw(name string, params QueryParameters) (*RawViewResponse, error)
+	GetRawContext(ctx context.Context, name string, params QueryParameters) (*RawViewResponse, error)
+	PostRaw(name string, keys interface{}, params QueryParameters) (*RawViewResponse, error)
+	PostRawContext(ctx context.Context, name string, keys interface{}, params QueryParameters) (*RawViewResponse, error)
+	Queries(name string, queries []QueryParameters) ([]ViewResponse, error)
+	QueriesContext(ctx context.Context, name string, queries []QueryParameters) ([]ViewResponse, error)
+	Show(name, docID string, query url.Values) (*HandlerResponse, error)
+	ShowContext(ctx context.Context, name, docID string, query url.Values) (*HandlerResponse, error)
+	List(name, view string, params QueryParameters, query url.Values) (*HandlerResponse, error)
+	ListContext(ctx context.Context, name, view string, params QueryParameters, query url.Values) (*HandlerResponse, error)
+	Update(name, docID string, query url.Values, body io.Reader, contentType string) (*HandlerResponse, error)
+	UpdateContext(ctx context.Context, name, docID string, query url.Values, body io.Reader, contentType string) (*HandlerResponse, error)
 }
 
 // View performs actions and certain view documents
@@ -23,12 +41,46 @@ type View struct {
 
 // Get executes specified view function from specified design document.
 func (v *View) Get(name string, params QueryParameters) (*ViewResponse, error) {
-	q, err := query.Values(params)
+	return v.GetContext(context.Background(), name, params)
+}
+
+// GetContext is like Get but includes a context.
+func (v *View) GetContext(ctx context.Context, name string, params QueryParameters) (*ViewResponse, error) {
+	var response ViewResponse
+	return &response, v.get(ctx, name, params, &response)
+}
+
+// get executes the view function and decodes the response into r.
+func (v *View) get(ctx context.Context, name string, params QueryParameters, r interface{}) error {
+	res, err := v.request(ctx, name, params)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	return json.NewDecoder(res.Body).Decode(r)
+}
+
+func (v *View) request(ctx context.Context, name string, params QueryParameters) (*http.Response, error) {
+	q, err := params.values()
 	if err != nil {
 		return nil, err
 	}
 	uri := fmt.Sprintf("%s_view/%s?%s", v.URL, name, q.Encode())
-	res, err := v.Client.Request(http.MethodGet, uri, nil, "")
+	return v.Client.RequestContext(ctx, http.MethodGet, uri, nil, "")
+}
+
+// Post executes specified view function from specified design document.
+// Unlike View.Get for accessing views, View.Post supports
+// the specification of explicit keys to be retrieved from the view results.
+// keys is encoded to a JSON array, e.g. []string{"john"} or [][]interface{}{{"user", 42}}.
+// params.Keys is used when keys is nil.
+func (v *View) Post(name string, keys interface{}, params QueryParameters) (*ViewResponse, error) {
+	return v.PostContext(context.Background(), name, keys, params)
+}
+
+// PostContext is like Post but includes a context.
+func (v *View) PostContext(ctx context.Context, name string, keys interface{}, params QueryParameters) (*ViewResponse, error) {
+	res, err := v.post(ctx, name, keys, params)
 	if err != nil {
 		return nil, err
 	}
@@ -37,12 +89,17 @@ func (v *View) Get(name string, params QueryParameters) (*ViewResponse, error) {
 	return &response, json.NewDecoder(res.Body).Decode(&response)
 }
 
-// Post executes specified view function from specified design document.
-// Unlike View.Get for accessing views, View.Post supports
-// the specification of explicit keys to be retrieved from the view results.
-func (v *View) Post(name string, keys []string, params QueryParameters) (*ViewResponse, error) {
+func (v *View) post(ctx context.Context, name string, keys interface{}, params QueryParameters) (*http.Response, error) {
+	if isNil(keys) {
+		keys = params.Keys
+	}
+	if isNil(keys) {
+		// CouchDB rejects null, no keys match no rows
+		keys = []interface{}{}
+	}
+	params.Keys = nil
 	content := struct {
-		Keys []string `json:"keys"`
+		Keys interface{} `json:"keys"`
 	}{
 		Keys: keys,
 	}
@@ -52,16 +109,52 @@ func (v *View) Post(name string, keys []string, params QueryParameters) (*ViewRe
 		return nil, err
 	}
 	// create query string
-	q, err := query.Values(params)
+	q, err := params.values()
 	if err != nil {
 		return nil, err
 	}
 	url := fmt.Sprintf("%s_view/%s?%s", v.URL, name, q.Encode())
-	res, err := v.Client.Request(http.MethodPost, url, &b, "application/json")
+	return v.Client.RequestContext(idempotent(ctx), http.MethodPost, url, &b, "application/json")
+}
+
+// GetRaw is like Get but keeps keys, values and documents encoded,
+// so they can be decoded into the caller's types.
+//
+//	res, err := view.GetRaw("countByName", couchdb.QueryParameters{Group: &group})
+//	for _, row := range res.Rows {
+//		var name string
+//		var count int
+//		if err := row.DecodeKey(&name); err != nil {
+//			return err
+//		}
+//		if err := row.DecodeValue(&count); err != nil {
+//			return err
+//		}
+//	}
+func (v *View) GetRaw(name string, params QueryParameters) (*RawViewResponse, error) {
+	return v.GetRawContext(context.Background(), name, params)
+}
+
+// GetRawContext is like GetRaw but includes a context.
+func (v *View) GetRawContext(ctx context.Context, name string, params QueryParameters) (*RawViewResponse, error) {
+	res, err := v.request(ctx, name, params)
 	if err != nil {
 		return nil, err
 	}
-	defer res.Body.Close()
-	var response ViewResponse
-	return &response, json.NewDecoder(res.Body).Decode(&response)
+	return decodeRawView(res)
+}
+
+// PostRaw is like Post but keeps keys, values and documents encoded.
+// Rows of keys without result have an Error.
+func (v *View) PostRaw(name string, keys interface{}, params QueryParameters) (*RawViewResponse, error) {
+	return v.PostRawContext(context.Background(), name, keys, params)
+}
+
+// PostRawContext is like PostRaw but includes a context.
+func (v *View) PostRawContext(ctx context.Context, name string, keys interface{}, params QueryParameters) (*RawViewResponse, error) {
+	res, err := v.post(ctx, name, keys, params)
+	if err != nil {
+		return nil, err
+	}
+	return decodeRawView(res)
 }
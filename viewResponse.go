@@ -1,11 +1,16 @@
 package couchdb
 
+import (
+	"encoding/json"
+	"net/http"
+)
+
 // ViewResponse is response for querying design documents.
 type ViewResponse struct {
 	Offset    int   `json:"offset,omitempty"`
 	Rows      []Row `json:"rows,omitempty"`
 	TotalRows int   `json:"total_rows,omitempty"`
-	UpdateSeq int   `json:"update_seq,omitempty"`
+	UpdateSeq Seq   `json:"update_seq,omitempty"`
 }
 
 // Row is single row inside design document query response.
@@ -14,4 +19,90 @@ type Row struct {
 	Key   interface{}            `json:"key"`
 	Value interface{}            `json:"value,omitempty"`
 	Doc   map[string]interface{} `json:"doc,omitempty"`
+	// Error is set for explicit keys without result, e.g. "not_found".
+	Error string `json:"error,omitempty"`
+}
+
+// RawViewResponse is a view response whose rows are decoded on demand into the caller's types.
+type RawViewResponse struct {
+	Offset    int      `json:"offset,omitempty"`
+	Rows      []RawRow `json:"rows,omitempty"`
+	TotalRows int      `json:"total_rows,omitempty"`
+	UpdateSeq Seq      `json:"update_seq,omitempty"`
+}
+
+// RawRow is a single row of a RawViewResponse.
+//
+//	for _, row := range res.Rows {
+//		var count int
+//		if err := row.DecodeValue(&count); err != nil {
+//			return err
+//		}
+//	}
+type RawRow struct {
+	ID    string          `json:"id"`
+	Key   json.RawMessage `json:"key"`
+	Value json.RawMessage `json:"value,omitempty"`
+	Doc   json.RawMessage `json:"doc,omitempty"`
+	// Error is set for explicit keys without result, e.g. missing documents of _all_docs.
+	Error *Error `json:"-"`
+}
+
+// UnmarshalJSON converts the error of a row into an *Error.
+func (r *RawRow) UnmarshalJSON(data []byte) error {
+	type rawRow RawRow
+	var row struct {
+		rawRow
+		Error  string `json:"error"`
+		Reason string `json:"reason"`
+	}
+	if err := json.Unmarshal(data, &row); err != nil {
+		return err
+	}
+	*r = RawRow(row.rawRow)
+	if row.Error != "" {
+		r.Error = docError("", "", row.Error, row.Reason)
+	}
+	return nil
+}
+
+// DecodeKey decodes the key of the row into v.
+func (r RawRow) DecodeKey(v interface{}) error {
+	return json.Unmarshal(r.Key, v)
+}
+
+// DecodeValue decodes the value of the row into v or returns the error of the row.
+func (r RawRow) DecodeValue(v interface{}) error {
+	if r.Error != nil {
+		return r.Error
+	}
+	return json.Unmarshal(r.Value, v)
+}
+
+// DecodeDoc decodes the document included with include_docs into doc or returns the error of the row.
+// An error matching ErrNotFound is returned when the document was deleted.
+func (r RawRow) DecodeDoc(doc CouchDoc) error {
+	if r.Error != nil {
+		return r.Error
+	}
+	if len(r.Doc) == 0 || string(r.Doc) == "null" {
+		return docError("", "", "not_found", "deleted")
+	}
+	return json.Unmarshal(r.Doc, decodeTarget(doc))
+}
+
+// decodeRawView decodes the response and adds the request to the errors of the rows.
+func decodeRawView(res *http.Response) (*RawViewResponse, error) {
+	defer res.Body.Close()
+	var response RawViewResponse
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return nil, err
+	}
+	for _, row := range response.Rows {
+		if row.Error != nil {
+			row.Error.Method = res.Request.Method
+			row.Error.URL = res.Request.URL.String()
+		}
+	}
+	return &response, nil
 }